@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Another0Noob/mangadex-import/internal/credentials"
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+)
+
+var (
+	flagUsername     string
+	flagPassword     string
+	flagPasswordFile string
+	flagClientID     string
+	flagClientSecret string
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(
+		&flagUsername,
+		"username",
+		"",
+		"MangaDex username (or "+credentials.EnvUsername+")",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&flagPassword,
+		"password",
+		"",
+		"MangaDex password; prefer --password-file, "+credentials.EnvPassword+" or the prompt",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&flagPasswordFile,
+		"password-file",
+		"",
+		"file holding the MangaDex password (or "+credentials.EnvPasswordFile+")",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&flagClientID,
+		"client-id",
+		"",
+		"API client ID (or "+credentials.EnvClientID+")",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&flagClientSecret,
+		"client-secret",
+		"",
+		"API client secret (or "+credentials.EnvClientSecret+")",
+	)
+}
+
+// loadAuth resolves the credentials from the flags, the MANGADEX_*
+// environment variables, the auth file and, on a terminal, a prompt for
+// whatever is still missing, in that order of precedence. The password is
+// not needed while a token is saved for the account.
+func loadAuth(client *mangadexapi.Client, authPath string) error {
+	sources := []credentials.Source{
+		credentials.Static("flags", credentials.Values{
+			credentials.FieldUsername:     flagUsername,
+			credentials.FieldPassword:     flagPassword,
+			credentials.FieldClientID:     flagClientID,
+			credentials.FieldClientSecret: flagClientSecret,
+		}),
+		credentials.SecretFile("--password-file", credentials.FieldPassword, flagPasswordFile),
+		credentials.Env(),
+	}
+	if authPath != "" {
+		sources = append(sources, credentials.File(authPath))
+	}
+	sources = append(sources,
+		credentials.SavedToken(client.HasSavedToken),
+		credentials.Prompt(os.Stdin, os.Stderr),
+	)
+
+	res, err := credentials.Resolve(sources...)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "Credentials:", res)
+	return client.LoadAuthForm(res.Form)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Another0Noob/mangadex-import/internal/match"
+	"github.com/spf13/cobra"
+)
+
+// explainCmd represents the explain command
+var explainCmd = &cobra.Command{
+	Use:   "explain [title...]",
+	Short: "Explain how import titles are matched",
+	Long: `Explain runs the direct, fuzzy and search matching stages for each title
+and prints the normalized form, index hits, fuzzy candidates, the search query
+and results, and why the final choice won or every stage was rejected.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExplain(authFile, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+
+	explainCmd.Flags().StringVarP(
+		&authFile,
+		"auth",
+		"a",
+		"",
+		"path to auth file (INI, JSON or TOML)",
+	)
+}
+
+func runExplain(authPath string, titles []string) error {
+	client := newClient()
+	ctx := context.Background()
+
+	if err := loadAuth(client, authPath); err != nil {
+		return fmt.Errorf("load auth: %w", err)
+	}
+
+	if err := client.EnsureToken(ctx); err != nil {
+		return fmt.Errorf("authenticate: %w", err)
+	}
+
+	followedManga, err := client.GetAllFollowed(ctx)
+	if err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+
+	for _, title := range titles {
+		printExplanation(match.Explain(ctx, client, followedManga, title))
+	}
+
+	return nil
+}
+
+func printExplanation(ex *match.Explanation) {
+	fmt.Printf("--- %s ---\n", ex.Input)
+	fmt.Printf("Normalized: %q\n", ex.Normalized)
+	for _, step := range ex.Rules {
+		fmt.Printf("  %-28s -> %q\n", step.Rule, step.Result)
+	}
+
+	fmt.Println("Direct:")
+	fmt.Printf("  MainTitleIndex: %v\n", ex.MainHits)
+	fmt.Printf("  AltTitleIndex:  %v\n", ex.AltHits)
+	fmt.Printf("  Owners:         %v\n", ex.Owners)
+
+	if ex.Threshold > 0 {
+		fmt.Printf("Fuzzy (threshold %d):\n", ex.Threshold)
+		for _, c := range ex.FuzzyCandidates {
+			if c.Distance < 0 {
+				fmt.Printf("  %q no match %v\n", c.Title, c.IDs)
+				continue
+			}
+			fmt.Printf("  %q distance %d %v\n", c.Title, c.Distance, c.IDs)
+		}
+	}
+
+	if ex.Searched {
+		fmt.Printf("Search: /manga?%s\n", ex.SearchQuery)
+		for _, r := range ex.SearchResults {
+			exact := ""
+			if r.ExactTitle {
+				exact = " (exact title)"
+			}
+			fmt.Printf("  %s %s%s\n", r.ID, r.Title, exact)
+		}
+	}
+
+	for _, r := range ex.Rejections {
+		fmt.Printf("Rejected %s\n", r)
+	}
+
+	if ex.Stage == "" {
+		fmt.Println("Result: unmatched")
+		fmt.Println()
+		return
+	}
+	fmt.Printf("Result: %s match https://mangadex.org/title/%s %s\n", ex.Stage, ex.ChosenID, ex.ChosenTitle)
+	fmt.Printf("  %s\n", ex.Reason)
+	for _, a := range ex.Alternatives {
+		fmt.Printf("  alternative %s %s (%s)\n", a.ID, a.Title, a.Reason)
+	}
+	fmt.Println()
+}
@@ -6,25 +6,35 @@ package main
 import (
 	"context"
 	"fmt"
-	"os"
 	"time"
 
+	"github.com/Another0Noob/mangadex-import/internal/backup"
+	"github.com/Another0Noob/mangadex-import/internal/export"
 	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
 	"github.com/spf13/cobra"
 )
 
+var (
+	exportFormat string
+	exportOutput string
+)
+
 // exportCmd represents the export command
 var exportCmd = &cobra.Command{
 	Use:   "export",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
+	Short: "Export followed manga from MangaDex",
+	Long: `Export writes your MangaDex follows in one of several formats:
+
+  urls    one https://mangadex.org/title/<id> line per manga (default)
+  mal     MAL-importable XML, using MangaDex's MAL links for manga_mangadb_id
+  comick  Comick-style CSV
+  json    titles, reading statuses, ratings and read chapter counts
+  backup  full account backup: follows, statuses, ratings, custom lists and
+          read chapters, restorable into another account
 
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+Without --output the file is named after the current date.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runExport(authFile)
+		return runExport(authFile, exportFormat, exportOutput)
 	},
 }
 
@@ -36,48 +46,112 @@ func init() {
 		"auth",
 		"a",
 		"",
-		"path to auth file",
+		"path to auth file (INI, JSON or TOML)",
 	)
-	exportCmd.MarkFlagRequired("auth")
 
+	exportCmd.Flags().StringVarP(
+		&exportFormat,
+		"format",
+		"f",
+		string(export.FormatURLs),
+		"output format: urls, mal, comick, json or backup",
+	)
+
+	exportCmd.Flags().StringVarP(
+		&exportOutput,
+		"output",
+		"o",
+		"",
+		"output file path",
+	)
 }
 
-func runExport(authPath string) error {
-	client := mangadexapi.NewClient()
+func runExport(authPath, formatName, outputPath string) error {
+	var format export.Format
+	if formatName != formatBackup {
+		var err error
+		if format, err = export.ParseFormat(formatName); err != nil {
+			return err
+		}
+	}
+
+	client := newClient()
 	ctx := context.Background()
 
-	err := client.LoadAuth(authPath)
+	err := loadAuth(client, authPath)
 	if err != nil {
 		return fmt.Errorf("load auth: %w", err)
 	}
 
-	if err := client.Authenticate(ctx); err != nil {
+	if err := client.EnsureToken(ctx); err != nil {
 		return fmt.Errorf("authenticate: %w", err)
 	}
 
+	if formatName == formatBackup {
+		return runBackupExport(ctx, client, outputPath)
+	}
+
 	fmt.Println("--- Requesting Mangadex Manga ---")
 
-	followedManga, err := client.GetAllFollowed(ctx)
+	items, err := export.Collect(ctx, client, format)
 	if err != nil {
 		return fmt.Errorf("request: %w", err)
 	}
 
-	fmt.Printf("Got %d MangaDex manga.\n", len(followedManga))
+	fmt.Printf("Got %d MangaDex manga.\n", len(items))
 
-	t := time.Now()
+	if outputPath == "" {
+		t := time.Now()
+		outputPath = fmt.Sprintf("%d-%d-%d-mangadex%s", t.Year(), t.Month(), t.Day(), format.Extension())
+	}
 
-	file, err := os.Create(fmt.Sprintf("%d-%d-%d-mangadex.txt", t.Year(), t.Month(), t.Day()))
-	if err != nil { // Check for an error during file creation
-		panic(err)
+	if err := export.WriteFile(outputPath, format, items); err != nil {
+		return fmt.Errorf("write export: %w", err)
 	}
-	defer file.Close()
 
-	for _, manga := range followedManga {
-		_, err = fmt.Fprintf(file, "https://mangadex.org/title/%v\n", manga.ID)
-		if err != nil {
-			return err
+	if format == export.FormatMAL {
+		missing := 0
+		for _, it := range items {
+			if it.Links["mal"] == "" {
+				missing++
+			}
 		}
+		if missing > 0 {
+			fmt.Printf("%d manga have no MAL link and will be skipped by MAL's importer.\n", missing)
+		}
+	}
+
+	fmt.Printf("Wrote %s.\n", outputPath)
+	return nil
+}
+
+const formatBackup = "backup"
+
+func runBackupExport(ctx context.Context, client *mangadexapi.Client, outputPath string) error {
+	fmt.Println("--- Requesting MangaDex library ---")
+
+	b, err := backup.Create(ctx, client)
+	if err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+
+	followed := 0
+	for _, e := range b.Manga {
+		if e.Followed {
+			followed++
+		}
+	}
+	fmt.Printf("Got %d manga (%d followed) and %d custom lists.\n", len(b.Manga), followed, len(b.Lists))
+
+	if outputPath == "" {
+		t := time.Now()
+		outputPath = fmt.Sprintf("%d-%d-%d-mangadex-backup.json", t.Year(), t.Month(), t.Day())
+	}
+
+	if err := b.WriteFile(outputPath); err != nil {
+		return fmt.Errorf("write backup: %w", err)
 	}
 
+	fmt.Printf("Wrote %s.\n", outputPath)
 	return nil
 }
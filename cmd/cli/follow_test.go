@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi/mdfake"
+	"github.com/Another0Noob/mangadex-import/internal/report"
+)
+
+const malInput = `<?xml version="1.0" encoding="UTF-8"?>
+<myanimelist>
+	<manga><manga_mangadb_id>1</manga_mangadb_id><manga_title>One Piece</manga_title><my_status>Reading</my_status></manga>
+	<manga><manga_mangadb_id>2</manga_mangadb_id><manga_title>Berserk</manga_title><my_status>Reading</my_status></manga>
+	<manga><manga_mangadb_id>3</manga_mangadb_id><manga_title>Vagabond</manga_title><my_status>Completed</my_status></manga>
+	<manga><manga_mangadb_id>4</manga_mangadb_id><manga_title>Not On MangaDex</manga_title><my_status>Reading</my_status></manga>
+</myanimelist>
+`
+
+// setupFollow starts a fake MangaDex with One Piece followed and Berserk and
+// Vagabond searchable, and writes auth and input files. The journal and
+// checkpoints go to a temporary config and cache dir.
+func setupFollow(t *testing.T) (srv *mdfake.Server, authPath, inputPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+
+	srv = mdfake.New()
+	t.Cleanup(srv.Close)
+	srv.AddManga(
+		mdfake.NewManga("op", "One Piece"),
+		mdfake.NewManga("bs", "Berserk"),
+		mdfake.NewManga("vb", "Vagabond"),
+	)
+	srv.Follow("op")
+
+	clientOpts = srv.Options()
+	t.Cleanup(func() { clientOpts = nil })
+
+	authPath = filepath.Join(dir, "auth.ini")
+	auth := "[mangadex]\nusername = " + mdfake.Username +
+		"\npassword = " + mdfake.Password +
+		"\nclient_id = " + mdfake.ClientID +
+		"\nclient_secret = " + mdfake.ClientSecret + "\n"
+	if err := os.WriteFile(authPath, []byte(auth), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	inputPath = filepath.Join(dir, "list.xml")
+	if err := os.WriteFile(inputPath, []byte(malInput), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return srv, authPath, inputPath
+}
+
+func TestRunFollow(t *testing.T) {
+	srv, authPath, inputPath := setupFollow(t)
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+
+	if err := runFollow(authPath, inputPath, reportPath, "", false); err != nil {
+		t.Fatalf("runFollow: %v", err)
+	}
+
+	followed := srv.Followed()
+	slices.Sort(followed)
+	if want := []string{"bs", "op", "vb"}; !slices.Equal(followed, want) {
+		t.Errorf("followed = %v, want %v", followed, want)
+	}
+	if n := srv.Count(http.MethodPost, "/manga/op/follow"); n != 0 {
+		t.Errorf("followed already followed manga %d times", n)
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rep report.Report
+	if err := json.Unmarshal(data, &rep); err != nil {
+		t.Fatal(err)
+	}
+	outcomes := make(map[string]string)
+	for _, row := range rep.Rows {
+		outcomes[row.ImportTitle] = row.Outcome
+	}
+	want := map[string]string{
+		"One Piece":       report.OutcomeAlreadyFollowed,
+		"Berserk":         report.OutcomeFollowed,
+		"Vagabond":        report.OutcomeFollowed,
+		"Not On MangaDex": report.OutcomeUnmatched,
+	}
+	for title, outcome := range want {
+		if outcomes[title] != outcome {
+			t.Errorf("%s: outcome %q, want %q", title, outcomes[title], outcome)
+		}
+	}
+}
+
+func TestRunFollowRejected(t *testing.T) {
+	srv, authPath, inputPath := setupFollow(t)
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+
+	srv.Fail(http.MethodPost, "/manga/vb/follow", http.StatusBadRequest, 1)
+	if err := runFollow(authPath, inputPath, reportPath, "", false); err != nil {
+		t.Fatalf("runFollow: %v", err)
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rep report.Report
+	if err := json.Unmarshal(data, &rep); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rep.Rows {
+		if row.ImportTitle != "Vagabond" {
+			continue
+		}
+		if row.Outcome != report.OutcomeFollowRejected || row.MangaDexID != "vb" {
+			t.Errorf("Vagabond: outcome %q for %q, want %q for vb", row.Outcome, row.MangaDexID, report.OutcomeFollowRejected)
+		}
+		return
+	}
+	t.Error("Vagabond missing from report")
+}
+
+func TestRunFollowResume(t *testing.T) {
+	srv, authPath, inputPath := setupFollow(t)
+
+	// Following Vagabond keeps failing past the client's retries.
+	srv.Fail(http.MethodPost, "/manga/vb/follow", http.StatusServiceUnavailable, 10)
+	if err := runFollow(authPath, inputPath, "", "", false); err == nil {
+		t.Fatal("runFollow succeeded despite failing follow")
+	}
+
+	srv.ClearFailures()
+	if err := runFollow(authPath, inputPath, "", "", true); err != nil {
+		t.Fatalf("resumed runFollow: %v", err)
+	}
+
+	if !slices.Contains(srv.Followed(), "vb") {
+		t.Errorf("Vagabond not followed after resume: %v", srv.Followed())
+	}
+	// Three titles are not followed yet; each is searched once across both
+	// runs.
+	if n := srv.Count(http.MethodGet, "/manga"); n != 3 {
+		t.Errorf("searched %d times across both runs, want 3", n)
+	}
+}
@@ -7,9 +7,9 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
 	"github.com/Another0Noob/mangadex-import/internal/mangaparser"
 	"github.com/Another0Noob/mangadex-import/internal/match"
+	"github.com/Another0Noob/mangadex-import/internal/report"
 	"github.com/spf13/cobra"
 )
 
@@ -24,7 +24,7 @@ Cobra is a CLI library for Go that empowers applications.
 This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runMatch(authFile, inputFile)
+		return runMatch(authFile, inputFile, reportFile, unmatchedFile)
 	},
 }
 
@@ -36,9 +36,8 @@ func init() {
 		"auth",
 		"a",
 		"",
-		"path to auth file",
+		"path to auth file (INI, JSON or TOML)",
 	)
-	matchCmd.MarkFlagRequired("auth")
 
 	matchCmd.Flags().StringVarP(
 		&inputFile,
@@ -48,9 +47,25 @@ func init() {
 		"path to input file",
 	)
 	matchCmd.MarkFlagRequired("input")
+
+	matchCmd.Flags().StringVarP(
+		&reportFile,
+		"report",
+		"r",
+		"",
+		"write a match report (.csv, .json or .html)",
+	)
+
+	matchCmd.Flags().StringVarP(
+		&unmatchedFile,
+		"unmatched",
+		"u",
+		"",
+		"write unmatched entries (.xml, .csv or .txt) plus a plain .txt list",
+	)
 }
 
-func runMatch(authPath, inputPath string) error {
+func runMatch(authPath, inputPath, reportPath, unmatchedPath string) error {
 	fmt.Println("--- Reading Manga ---")
 
 	inputManga, err := mangaparser.Parse(inputPath)
@@ -60,14 +75,22 @@ func runMatch(authPath, inputPath string) error {
 
 	fmt.Printf("Got %d manga.\n", len(inputManga))
 
-	client := mangadexapi.NewClient()
+	inputManga, duplicates := match.DedupeEntries(inputManga)
+	for _, d := range duplicates {
+		fmt.Printf("Merged duplicate rows %v (%s match): %s\n", d.Rows, d.Reason, d.Title)
+	}
+	if len(duplicates) > 0 {
+		fmt.Printf("%d unique manga after merging duplicates.\n", len(inputManga))
+	}
+
+	client := newClient()
 	ctx := context.Background()
 
-	if err := client.LoadAuth(authPath); err != nil {
+	if err := loadAuth(client, authPath); err != nil {
 		return fmt.Errorf("load auth: %w", err)
 	}
 
-	if err := client.Authenticate(ctx); err != nil {
+	if err := client.EnsureToken(ctx); err != nil {
 		return fmt.Errorf("authenticate: %w", err)
 	}
 
@@ -91,5 +114,22 @@ func runMatch(authPath, inputPath string) error {
 
 	fmt.Printf("%d manga remaining.\n", len(matchResult.Unmatched.Import))
 
+	if reportPath != "" {
+		rep := report.New(inputPath)
+		rep.AddMatches(matchResult.Matches, "", report.OutcomeAlreadyFollowed)
+		rep.AddUnmatched(matchResult.Unmatched.Import)
+		rep.Sort()
+		if err := rep.WriteFile(reportPath); err != nil {
+			return fmt.Errorf("write report: %w", err)
+		}
+		fmt.Printf("Wrote report to %s.\n", reportPath)
+	}
+
+	if unmatchedPath != "" {
+		if err := writeUnmatched(unmatchedPath, matchResult.Unmatched.Import); err != nil {
+			return fmt.Errorf("write unmatched: %w", err)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+	"github.com/Another0Noob/mangadex-import/internal/mangaparser"
+	"github.com/Another0Noob/mangadex-import/internal/match"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pruneDryRun bool
+	pruneYes    bool
+)
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Unfollow MangaDex titles that are not in the import list",
+	Long: `Prune matches the import list against your MangaDex follows like "match"
+does and unfollows every followed title that no import entry matched, so that
+your follows mirror the source list exactly.
+
+The titles to unfollow are listed first and you are asked to confirm, unless
+--yes is given. Use --dry-run to only print them. Import entries that found no
+direct or fuzzy match are reported too: if one of them is a followed title
+under a different name, fix or remove it before pruning. Followed titles that
+share their title with such an entry are kept, since the entry could mean any
+of them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPrune(authFile, inputFile, pruneDryRun, pruneYes)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+
+	pruneCmd.Flags().StringVarP(
+		&authFile,
+		"auth",
+		"a",
+		"",
+		"path to auth file (INI, JSON or TOML)",
+	)
+
+	pruneCmd.Flags().StringVarP(
+		&inputFile,
+		"input",
+		"i",
+		"",
+		"path to input file",
+	)
+	pruneCmd.MarkFlagRequired("input")
+
+	pruneCmd.Flags().BoolVarP(
+		&pruneDryRun,
+		"dry-run",
+		"n",
+		false,
+		"print the titles to unfollow without unfollowing them",
+	)
+
+	pruneCmd.Flags().BoolVarP(
+		&pruneYes,
+		"yes",
+		"y",
+		false,
+		"unfollow without asking for confirmation",
+	)
+}
+
+func runPrune(authPath, inputPath string, dryRun, yes bool) error {
+	fmt.Println("--- Reading Manga ---")
+
+	inputManga, err := mangaparser.Parse(inputPath)
+	if err != nil {
+		return fmt.Errorf("parse file: %w", err)
+	}
+	inputManga, _ = match.DedupeEntries(inputManga)
+
+	fmt.Printf("Got %d manga.\n", len(inputManga))
+
+	client := newClient()
+	ctx := context.Background()
+
+	if err := loadAuth(client, authPath); err != nil {
+		return fmt.Errorf("load auth: %w", err)
+	}
+
+	if err := client.EnsureToken(ctx); err != nil {
+		return fmt.Errorf("authenticate: %w", err)
+	}
+
+	fmt.Println("--- Requesting Mangadex Manga ---")
+
+	followedManga, err := client.GetAllFollowed(ctx)
+	if err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+
+	fmt.Printf("Got %d MangaDex manga.\n", len(followedManga))
+
+	fmt.Println("--- Matching Manga ---")
+
+	matchResult := match.FuzzyMatch(match.MatchDirect(followedManga, inputManga))
+	fmt.Printf("Matched %d manga.\n", len(matchResult.Matches))
+
+	if n := len(matchResult.Unmatched.Import); n > 0 {
+		fmt.Printf("%d import entries matched no followed title:\n", n)
+		for _, e := range matchResult.Unmatched.Import {
+			fmt.Printf("  %s\n", e.Original)
+		}
+	}
+
+	// A title shared by several follows matches none of them; keep them all
+	// rather than unfollow the one the import entry means.
+	ambiguous := matchResult.Unmatched.Ambiguous()
+	if len(ambiguous) > 0 {
+		fmt.Printf("--- %d followed manga share a title with an import entry, kept ---\n", len(ambiguous))
+		for _, m := range ambiguous {
+			fmt.Printf("  %s %s\n", m.URL(), match.DisplayTitle(m))
+		}
+	}
+	keep := make(map[string]bool, len(ambiguous))
+	for _, m := range ambiguous {
+		keep[m.ID] = true
+	}
+	var toUnfollow []mangadexapi.Manga
+	for _, m := range matchResult.Unmatched.MD {
+		if !keep[m.ID] {
+			toUnfollow = append(toUnfollow, m)
+		}
+	}
+	if len(toUnfollow) == 0 {
+		fmt.Println("Nothing to unfollow.")
+		return nil
+	}
+
+	fmt.Printf("--- %d followed manga not in the import list ---\n", len(toUnfollow))
+	for _, m := range toUnfollow {
+		fmt.Printf("  %s %s\n", m.URL(), match.DisplayTitle(m))
+	}
+
+	if dryRun {
+		fmt.Println("Dry run, nothing unfollowed.")
+		return nil
+	}
+
+	if !yes && !confirm(fmt.Sprintf("Unfollow %d manga?", len(toUnfollow))) {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	j, err := startJournal(client, "prune")
+	if err != nil {
+		return err
+	}
+	defer finishJournal(j)
+
+	fmt.Println("--- Unfollowing ---")
+
+	unfollowed := 0
+	for _, m := range toUnfollow {
+		if err := client.UnfollowManga(ctx, m.ID); err != nil {
+			fmt.Printf("Unfollowed %d of %d manga.\n", unfollowed, len(toUnfollow))
+			return fmt.Errorf("unfollow %s: %w", m.ID, err)
+		}
+		unfollowed++
+	}
+	fmt.Printf("Unfollowed %d manga.\n", unfollowed)
+
+	return nil
+}
+
+// confirm asks a yes/no question on stdin; anything but "y" or "yes" is no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi/mdfake"
+)
+
+func TestRunPruneKeepsAmbiguous(t *testing.T) {
+	srv, authPath, inputPath := setupFollow(t)
+	srv.AddManga(
+		mdfake.NewManga("bs2", "Berserk"),
+		mdfake.NewManga("mo", "Monster"),
+	)
+	srv.Follow("bs", "bs2", "mo")
+
+	if err := runPrune(authPath, inputPath, false, true); err != nil {
+		t.Fatalf("runPrune: %v", err)
+	}
+
+	// Both Berserk follows own the import entry's title, so neither is
+	// matched, and neither may be unfollowed.
+	followed := srv.Followed()
+	slices.Sort(followed)
+	if want := []string{"bs", "bs2", "op"}; !slices.Equal(followed, want) {
+		t.Errorf("followed = %v, want %v", followed, want)
+	}
+}
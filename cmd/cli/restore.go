@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Another0Noob/mangadex-import/internal/backup"
+	"github.com/spf13/cobra"
+)
+
+var restoreDryRun bool
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore a MangaDex backup into the authenticated account",
+	Long: `Restore reapplies follows, reading statuses, ratings and custom list
+memberships from a backup written by "export --format backup". Manga are
+matched by UUID, so no title matching is involved.
+
+The current account state is compared with the backup first and only the
+differences are applied. Nothing is removed: follows, statuses, ratings and
+list entries that are not in the backup are left untouched.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRestore(authFile, inputFile, restoreDryRun)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.Flags().StringVarP(
+		&authFile,
+		"auth",
+		"a",
+		"",
+		"path to auth file (INI, JSON or TOML)",
+	)
+
+	restoreCmd.Flags().StringVarP(
+		&inputFile,
+		"input",
+		"i",
+		"",
+		"path to backup file",
+	)
+	restoreCmd.MarkFlagRequired("input")
+
+	restoreCmd.Flags().BoolVarP(
+		&restoreDryRun,
+		"dry-run",
+		"n",
+		false,
+		"print the changes without applying them",
+	)
+}
+
+func runRestore(authPath, inputPath string, dryRun bool) error {
+	b, err := backup.Load(inputPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Backup from %s: %d manga, %d custom lists.\n", b.CreatedAt.Format("2006-01-02 15:04"), len(b.Manga), len(b.Lists))
+
+	client := newClient()
+	ctx := context.Background()
+
+	if err := loadAuth(client, authPath); err != nil {
+		return fmt.Errorf("load auth: %w", err)
+	}
+
+	if err := client.EnsureToken(ctx); err != nil {
+		return fmt.Errorf("authenticate: %w", err)
+	}
+
+	fmt.Println("--- Comparing with current account ---")
+
+	changes, err := backup.Diff(ctx, client, b)
+	if err != nil {
+		return fmt.Errorf("diff: %w", err)
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("Account already matches the backup.")
+		return nil
+	}
+
+	counts := make(map[string]int)
+	for _, c := range changes {
+		counts[c.Kind]++
+		if dryRun {
+			fmt.Println(c)
+		}
+	}
+	for _, kind := range []string{backup.ChangeFollow, backup.ChangeStatus, backup.ChangeRating, backup.ChangeCreateList, backup.ChangeAddToList} {
+		if counts[kind] > 0 {
+			fmt.Printf("%s: %d\n", kind, counts[kind])
+		}
+	}
+
+	if dryRun {
+		fmt.Println("Dry run, nothing applied.")
+		return nil
+	}
+
+	j, err := startJournal(client, "restore")
+	if err != nil {
+		return err
+	}
+	defer finishJournal(j)
+
+	fmt.Println("--- Applying changes ---")
+
+	applied := 0
+	err = backup.Apply(ctx, client, changes, func(c backup.Change, err error) {
+		if err == nil {
+			applied++
+			fmt.Println(c)
+		}
+	})
+	fmt.Printf("Applied %d of %d changes.\n", applied, len(changes))
+	return err
+}
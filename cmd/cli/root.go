@@ -3,43 +3,136 @@ package main
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/Another0Noob/mangadex-import/internal/checkpoint"
 	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
 	"github.com/Another0Noob/mangadex-import/internal/mangaparser"
 	"github.com/Another0Noob/mangadex-import/internal/match"
+	"github.com/Another0Noob/mangadex-import/internal/report"
 	"github.com/spf13/cobra"
 )
 
 var (
-	authFile  string
-	inputFile string
+	authFile      string
+	inputFile     string
+	reportFile    string
+	unmatchedFile string
+	resumeRun     bool
+	searchWorkers int
+
+	apiURL     string
+	tokenURL   string
+	proxyURL   string
+	reqTimeout time.Duration
+	recordFile string
+	replayFile string
+	useCache   bool
+	cacheTTL   time.Duration
+	clientOpts []mangadexapi.Option
+	recorder   *mangadexapi.Recorder
+	cache      *mangadexapi.Cache
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "mangadex-import",
 	Short: "A brief description of your application",
 	Long:  `...`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return setClientOptions()
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runFollow(authFile, inputFile)
+		return runFollow(authFile, inputFile, reportFile, unmatchedFile, resumeRun)
 	},
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	if cache != nil {
+		fmt.Fprintln(os.Stderr, "Cache:", cache.Stats())
+	}
+	if recorder != nil {
+		if serr := recorder.Save(recordFile); serr != nil {
+			fmt.Fprintln(os.Stderr, "Error:", serr)
+		} else {
+			fmt.Fprintln(os.Stderr, "Recorded API interactions to", recordFile)
+		}
+	}
+	if err != nil {
 		os.Exit(1)
 	}
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(
+		&apiURL,
+		"api-url",
+		"",
+		"MangaDex API URL, e.g. https://api.mangadex.dev for the sandbox",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&tokenURL,
+		"auth-url",
+		"",
+		"MangaDex OAuth token URL",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&proxyURL,
+		"proxy",
+		"",
+		"HTTP(S) proxy for MangaDex requests",
+	)
+
+	rootCmd.PersistentFlags().DurationVar(
+		&reqTimeout,
+		"timeout",
+		0,
+		"timeout for each MangaDex request, 0 for none",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&recordFile,
+		"record",
+		"",
+		"record MangaDex requests and responses to this file, with credentials scrubbed",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&replayFile,
+		"replay",
+		"",
+		"serve MangaDex responses from a file made with --record instead of the network",
+	)
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay")
+
+	rootCmd.PersistentFlags().BoolVar(
+		&useCache,
+		"cache",
+		false,
+		"cache manga, search and follow list responses under the user cache dir",
+	)
+
+	rootCmd.PersistentFlags().DurationVar(
+		&cacheTTL,
+		"cache-ttl",
+		0,
+		"how long cached responses stay fresh, 0 for the defaults (1h follows, 6h searches, 24h manga)",
+	)
+
 	rootCmd.Flags().StringVarP(
 		&authFile,
 		"auth",
 		"a",
 		"",
-		"path to auth file",
+		"path to auth file (INI, JSON or TOML)",
 	)
-	rootCmd.MarkFlagRequired("auth")
 
 	rootCmd.Flags().StringVarP(
 		&inputFile,
@@ -49,9 +142,40 @@ func init() {
 		"path to input file",
 	)
 	rootCmd.MarkFlagRequired("input")
+
+	rootCmd.Flags().StringVarP(
+		&reportFile,
+		"report",
+		"r",
+		"",
+		"write a match report (.csv, .json or .html)",
+	)
+
+	rootCmd.Flags().StringVarP(
+		&unmatchedFile,
+		"unmatched",
+		"u",
+		"",
+		"write unmatched entries (.xml, .csv or .txt) plus a plain .txt list",
+	)
+
+	rootCmd.Flags().BoolVar(
+		&resumeRun,
+		"resume",
+		false,
+		"resume an interrupted run with the same input file and account",
+	)
+
+	rootCmd.Flags().IntVarP(
+		&searchWorkers,
+		"workers",
+		"w",
+		4,
+		"number of titles searched concurrently (requests stay rate limited)",
+	)
 }
 
-func runFollow(authPath, inputPath string) error {
+func runFollow(authPath, inputPath, reportPath, unmatchedPath string, resume bool) error {
 	fmt.Println("--- Reading Manga ---")
 
 	inputManga, err := mangaparser.Parse(inputPath)
@@ -61,17 +185,31 @@ func runFollow(authPath, inputPath string) error {
 
 	fmt.Printf("Got %d manga.\n", len(inputManga))
 
-	client := mangadexapi.NewClient()
+	inputManga, duplicates := match.DedupeEntries(inputManga)
+	for _, d := range duplicates {
+		fmt.Printf("Merged duplicate rows %v (%s match): %s\n", d.Rows, d.Reason, d.Title)
+	}
+	if len(duplicates) > 0 {
+		fmt.Printf("%d unique manga after merging duplicates.\n", len(inputManga))
+	}
+
+	client := newClient()
 	ctx := context.Background()
 
-	if err := client.LoadAuth(authPath); err != nil {
+	if err := loadAuth(client, authPath); err != nil {
 		return fmt.Errorf("load auth: %w", err)
 	}
 
-	if err := client.Authenticate(ctx); err != nil {
+	if err := client.EnsureToken(ctx); err != nil {
 		return fmt.Errorf("authenticate: %w", err)
 	}
 
+	j, err := startJournal(client, "follow")
+	if err != nil {
+		return err
+	}
+	defer finishJournal(j)
+
 	fmt.Println("--- Requesting Mangadex Manga ---")
 
 	followedManga, err := client.GetAllFollowed(ctx)
@@ -95,13 +233,175 @@ func runFollow(authPath, inputPath string) error {
 	// Search for unmatched manga
 	fmt.Println("--- Searching for unmatched manga ---")
 
-	newMatches, stillUnmatched, err := match.SearchAndFollow(ctx, client, matchResult.Unmatched.Import, true)
+	cp, err := openCheckpoint(inputPath, client.Username(), resume)
 	if err != nil {
+		return err
+	}
+	if n := cp.Len(); n > 0 {
+		fmt.Printf("Resuming, %d manga already searched.\n", n)
+	}
+
+	searchResult, err := match.SearchAndFollow(ctx, client, matchResult.Unmatched.Import, match.SearchOptions{
+		Follow:     true,
+		Followed:   match.FollowedIDs(followedManga),
+		Checkpoint: cp,
+		Workers:    searchWorkers,
+	})
+	if err != nil {
+		cp.Close()
+		fmt.Println("Progress saved, rerun with --resume to continue.")
 		return fmt.Errorf("Search: %w", err)
 	}
+	if err := cp.Remove(); err != nil {
+		fmt.Printf("Removing checkpoint: %v\n", err)
+	}
+
+	newMatches, stillUnmatched := searchResult.New, searchResult.Unmatched
 
 	fmt.Printf("\nFound %d new matches.\n", len(newMatches))
+	if n := len(searchResult.AlreadyFollowed); n > 0 {
+		fmt.Printf("%d matches were already followed.\n", n)
+	}
+	if n := len(searchResult.FollowRejected); n > 0 {
+		fmt.Printf("%d matches could not be followed.\n", n)
+	}
 	fmt.Printf("%d manga remain unmatched.\n", len(stillUnmatched))
 
+	if reportPath != "" {
+		rep := report.New(inputPath)
+		rep.AddMatches(matchResult.Matches, "", report.OutcomeAlreadyFollowed)
+		rep.AddMatches(newMatches, "search", report.OutcomeFollowed)
+		rep.AddMatches(searchResult.AlreadyFollowed, "search", report.OutcomeAlreadyFollowedSearch)
+		rep.AddMatches(searchResult.FollowRejected, "search", report.OutcomeFollowRejected)
+		rep.AddUnmatched(stillUnmatched)
+		rep.Sort()
+		if err := rep.WriteFile(reportPath); err != nil {
+			return fmt.Errorf("write report: %w", err)
+		}
+		fmt.Printf("Wrote report to %s.\n", reportPath)
+	}
+
+	if unmatchedPath != "" {
+		if err := writeUnmatched(unmatchedPath, stillUnmatched); err != nil {
+			return fmt.Errorf("write unmatched: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// setClientOptions turns the persistent connection flags into client options.
+func setClientOptions() error {
+	clientOpts = nil
+	recorder = nil
+	cache = nil
+	if apiURL != "" {
+		clientOpts = append(clientOpts, mangadexapi.WithBaseURL(apiURL))
+	}
+	if tokenURL != "" {
+		clientOpts = append(clientOpts, mangadexapi.WithAuthURL(tokenURL))
+	}
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return fmt.Errorf("parse proxy: %w", err)
+		}
+		clientOpts = append(clientOpts, mangadexapi.WithProxy(u))
+	}
+	if reqTimeout > 0 {
+		clientOpts = append(clientOpts, mangadexapi.WithTimeout(reqTimeout))
+	}
+
+	if useCache && replayFile == "" {
+		var err error
+		if cache, err = openCache(); err != nil {
+			return err
+		}
+		clientOpts = append(clientOpts, mangadexapi.WithCache(cache))
+	}
+
+	switch {
+	case recordFile != "":
+		// Applied last so that it wraps the proxy transport.
+		recorder = mangadexapi.NewRecorder(nil)
+		clientOpts = append(clientOpts, mangadexapi.WithRecorder(recorder))
+	case replayFile != "":
+		replayer, err := mangadexapi.LoadReplayer(replayFile)
+		if err != nil {
+			return err
+		}
+		// Nothing goes over the network, so skip the rate limits.
+		clientOpts = append(clientOpts,
+			mangadexapi.WithTransport(replayer),
+			mangadexapi.WithRateLimiter(nil),
+			mangadexapi.WithWriteRateLimiter(nil),
+			mangadexapi.WithAuthRateLimiter(nil),
+		)
+	}
+	return nil
+}
+
+// newClient creates the API client used by every command. Tokens are saved
+// per account so that the next run need not log in again.
+func newClient() *mangadexapi.Client {
+	opts := clientOpts
+	// Recordings must contain the login, and replayed tokens are fake.
+	if recordFile == "" && replayFile == "" {
+		if dir, err := mangadexapi.DefaultTokenDir(); err == nil {
+			opts = append(opts[:len(opts):len(opts)], mangadexapi.WithTokenDir(dir))
+		}
+	}
+	client := mangadexapi.NewClient(opts...)
+	client.SetRetryObserver(printRetry)
+	return client
+}
+
+// printRetry reports a request that is retried after rate limiting or a
+// server error.
+func printRetry(ev mangadexapi.RetryEvent) {
+	reason := fmt.Sprintf("status %d", ev.Status)
+	if ev.Status == http.StatusTooManyRequests {
+		reason = "rate limited"
+	}
+	if ev.Err != nil {
+		reason = ev.Err.Error()
+	}
+	fmt.Printf("%s %s: %s, retrying in %s (attempt %d).\n", ev.Method, ev.Endpoint, reason, ev.Delay.Round(time.Millisecond), ev.Attempt+1)
+}
+
+// openCheckpoint opens the search checkpoint for this input file and account.
+func openCheckpoint(inputPath, account string, resume bool) (*checkpoint.Store, error) {
+	input, err := os.ReadFile(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("read input: %w", err)
+	}
+	dir, err := checkpoint.DefaultDir()
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint dir: %w", err)
+	}
+	return checkpoint.Open(dir, checkpoint.Key(input, account), resume)
+}
+
+// writeUnmatched writes entries to path in the format given by its extension,
+// and a plain title list next to it, so they can be fixed and re-imported.
+func writeUnmatched(path string, entries []match.ImportEntry) error {
+	sources := make([]mangaparser.Entry, len(entries))
+	for i, e := range entries {
+		sources[i] = e.Source
+	}
+
+	if err := mangaparser.WriteFile(path, sources); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d unmatched manga to %s.\n", len(sources), path)
+
+	listPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".txt"
+	if listPath == path {
+		return nil
+	}
+	if err := mangaparser.WriteFile(listPath, sources); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote unmatched titles to %s.\n", listPath)
 	return nil
 }
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+	"github.com/Another0Noob/mangadex-import/internal/mangaparser"
+	"github.com/Another0Noob/mangadex-import/internal/match"
+	"github.com/Another0Noob/mangadex-import/internal/syncer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncPolicy        string
+	syncApply         bool
+	syncFollowMissing bool
+	syncWriteSource   string
+	syncStateFile     string
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Compare a MAL/AniList export with MangaDex and reconcile statuses",
+	Long: `Sync compares a MAL export (AniList exports in MAL XML format work too)
+with your MangaDex follows and reading statuses, and prints:
+
+  only in source    titles not followed on MangaDex
+  only in MangaDex  followed titles missing from the source list
+  conflicts         titles whose reading status differs
+
+Conflicts are resolved with --policy: "source" or "mangadex" always win,
+"newest" picks the side that changed since the last sync, using MAL's
+my_last_updated dates and the MangaDex statuses recorded by the previous run.
+
+Nothing is written unless --apply is given. With --apply, conflicts won by the
+source are written to MangaDex, and --follow-missing searches for and follows
+source-only titles. --write-source writes the source list updated with
+MangaDex-won statuses and MangaDex-only titles, for importing back into
+MAL/AniList. Running sync again after --apply is a no-op.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSync(authFile, inputFile)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().StringVarP(
+		&authFile,
+		"auth",
+		"a",
+		"",
+		"path to auth file (INI, JSON or TOML)",
+	)
+
+	syncCmd.Flags().StringVarP(
+		&inputFile,
+		"input",
+		"i",
+		"",
+		"path to source export file",
+	)
+	syncCmd.MarkFlagRequired("input")
+
+	syncCmd.Flags().StringVarP(
+		&syncPolicy,
+		"policy",
+		"p",
+		string(syncer.PolicyNewest),
+		"conflict policy: source, mangadex or newest",
+	)
+
+	syncCmd.Flags().BoolVar(
+		&syncApply,
+		"apply",
+		false,
+		"write changes to MangaDex",
+	)
+
+	syncCmd.Flags().BoolVar(
+		&syncFollowMissing,
+		"follow-missing",
+		false,
+		"with --apply, search for and follow titles only in the source",
+	)
+
+	syncCmd.Flags().StringVarP(
+		&syncWriteSource,
+		"write-source",
+		"w",
+		"",
+		"write the updated source list (.xml, .csv or .txt)",
+	)
+
+	syncCmd.Flags().StringVar(
+		&syncStateFile,
+		"state",
+		"",
+		"path to sync state file (default: one per account and source list in the config dir)",
+	)
+}
+
+func runSync(authPath, inputPath string) error {
+	policy, err := syncer.ParsePolicy(syncPolicy)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("--- Reading Manga ---")
+
+	inputManga, err := mangaparser.Parse(inputPath)
+	if err != nil {
+		return fmt.Errorf("parse file: %w", err)
+	}
+	inputManga, _ = match.DedupeEntries(inputManga)
+
+	fmt.Printf("Got %d manga.\n", len(inputManga))
+
+	client := newClient()
+	ctx := context.Background()
+
+	if err := loadAuth(client, authPath); err != nil {
+		return fmt.Errorf("load auth: %w", err)
+	}
+
+	if err := client.EnsureToken(ctx); err != nil {
+		return fmt.Errorf("authenticate: %w", err)
+	}
+
+	// The state of the last sync is only meaningful for the same account and
+	// source list.
+	source, err := filepath.Abs(inputPath)
+	if err != nil {
+		return err
+	}
+	statePath := syncStateFile
+	if statePath == "" {
+		if statePath, err = syncer.DefaultStatePath(client.Username(), source); err != nil {
+			return fmt.Errorf("sync state dir: %w", err)
+		}
+	}
+	state, err := syncer.LoadState(statePath, client.Username(), source)
+	if err != nil {
+		return err
+	}
+
+	j, err := startJournal(client, "sync")
+	if err != nil {
+		return err
+	}
+	defer finishJournal(j)
+
+	fmt.Println("--- Requesting Mangadex Manga ---")
+
+	followedManga, err := client.GetAllFollowed(ctx)
+	if err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	statuses, err := client.GetMangaStatusList(ctx, mangadexapi.QueryParams{})
+	if err != nil {
+		return fmt.Errorf("request statuses: %w", err)
+	}
+
+	fmt.Printf("Got %d MangaDex manga.\n", len(followedManga))
+
+	fmt.Println("--- Comparing ---")
+
+	diff := syncer.Compare(followedManga, statuses, inputManga, policy, state)
+
+	fmt.Printf("Matched %d manga.\n", len(diff.Matched))
+	fmt.Printf("%d only in source, %d only in MangaDex, %d status conflicts.\n",
+		len(diff.OnlyInSource), len(diff.OnlyInMangaDex), len(diff.Conflicts))
+
+	for _, c := range diff.Conflicts {
+		winner := c.Winner
+		if winner == syncer.WinnerNone {
+			winner = "unresolved"
+		}
+		fmt.Printf("  %s: source %q, MangaDex %q -> %s (%s)\n",
+			c.Title, c.SourceStatus, c.MangaDexStatus, winner, c.Reason)
+	}
+
+	if syncApply {
+		fmt.Println("--- Applying ---")
+
+		applied, err := diff.Apply(ctx, client, nil)
+		fmt.Printf("Updated %d MangaDex statuses.\n", applied)
+		if err != nil {
+			return err
+		}
+
+		if syncFollowMissing && len(diff.OnlyInSource) > 0 {
+			followed, err := diff.FollowMissing(ctx, client)
+			fmt.Printf("Followed %d manga, %d remain only in source.\n", followed, len(diff.OnlyInSource))
+			if err != nil {
+				return fmt.Errorf("follow missing: %w", err)
+			}
+		}
+
+		diff.Record(state)
+		if err := state.Save(statePath); err != nil {
+			return fmt.Errorf("save sync state: %w", err)
+		}
+	} else {
+		fmt.Println("Dry run, use --apply to write changes.")
+	}
+
+	if syncWriteSource != "" {
+		if err := mangaparser.WriteFile(syncWriteSource, diff.SourceEntries(inputManga)); err != nil {
+			return fmt.Errorf("write source: %w", err)
+		}
+		fmt.Printf("Wrote updated source list to %s.\n", syncWriteSource)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Another0Noob/mangadex-import/internal/journal"
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+	"github.com/spf13/cobra"
+)
+
+var (
+	undoList   bool
+	undoDryRun bool
+)
+
+// undoCmd represents the undo command
+var undoCmd = &cobra.Command{
+	Use:   "undo [run]",
+	Short: "Revert the follows, statuses and ratings written by a run",
+	Long: `Every follow, unfollow, status and rating change made by the importer is
+journaled together with the state it replaced. Undo reverts the writes of the
+last run (or the given run) in reverse order: newly followed titles are
+unfollowed, unfollowed ones followed again, and previous statuses and ratings
+restored. The run is then marked undone.
+
+Only runs made on the account of the loaded credentials are reverted.
+
+Use --list to show the journaled runs.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if undoList {
+			return runUndoList()
+		}
+		run := ""
+		if len(args) > 0 {
+			run = args[0]
+		}
+		return runUndo(authFile, run, undoDryRun)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+
+	undoCmd.Flags().StringVarP(
+		&authFile,
+		"auth",
+		"a",
+		"",
+		"path to auth file (INI, JSON or TOML)",
+	)
+
+	undoCmd.Flags().BoolVarP(
+		&undoList,
+		"list",
+		"l",
+		false,
+		"list journaled runs",
+	)
+
+	undoCmd.Flags().BoolVarP(
+		&undoDryRun,
+		"dry-run",
+		"n",
+		false,
+		"print the writes to revert without reverting them",
+	)
+}
+
+// startJournal journals the client's writes for the undo command.
+func startJournal(client *mangadexapi.Client, command string) (*journal.Journal, error) {
+	dir, err := journal.DefaultDir()
+	if err != nil {
+		return nil, fmt.Errorf("journal dir: %w", err)
+	}
+	return journal.Start(dir, command, client), nil
+}
+
+// finishJournal closes the journal and tells the user how to revert the run.
+func finishJournal(j *journal.Journal) {
+	if err := j.Close(); err != nil {
+		fmt.Printf("Closing journal: %v\n", err)
+	}
+	if n := j.Len(); n > 0 {
+		fmt.Printf("Journaled %d writes as run %s, revert with \"undo %s\".\n", n, j.Run, j.Run)
+	}
+}
+
+func runUndoList() error {
+	dir, err := journal.DefaultDir()
+	if err != nil {
+		return fmt.Errorf("journal dir: %w", err)
+	}
+	runs, err := journal.List(dir)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		fmt.Println("No journaled runs.")
+		return nil
+	}
+	for _, r := range runs {
+		state := ""
+		if r.Undone {
+			state = " (undone)"
+		}
+		fmt.Printf("%s  %-8s %-16s %d writes%s\n", r.Run, r.Command, r.Username, len(r.Records), state)
+	}
+	return nil
+}
+
+func runUndo(authPath, runID string, dryRun bool) error {
+	dir, err := journal.DefaultDir()
+	if err != nil {
+		return fmt.Errorf("journal dir: %w", err)
+	}
+
+	client := newClient()
+	ctx := context.Background()
+
+	// Runs are picked for, and only reverted on, the account that made them.
+	if err := loadAuth(client, authPath); err != nil {
+		return fmt.Errorf("load auth: %w", err)
+	}
+
+	run, err := journal.Find(dir, runID, client.Username())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Run %s (%s, %s): %d writes.\n", run.Run, run.Command, run.Started.Format("2006-01-02 15:04"), len(run.Records))
+
+	if dryRun {
+		for i := len(run.Records) - 1; i >= 0; i-- {
+			fmt.Printf("revert %s\n", run.Records[i])
+		}
+		fmt.Println("Dry run, nothing reverted.")
+		return nil
+	}
+
+	if err := client.EnsureToken(ctx); err != nil {
+		return fmt.Errorf("authenticate: %w", err)
+	}
+
+	fmt.Println("--- Reverting ---")
+
+	reverted := 0
+	err = journal.Undo(ctx, client, run, func(rec journal.Record, err error) {
+		if err == nil {
+			reverted++
+			fmt.Printf("reverted %s\n", rec)
+		}
+	})
+	fmt.Printf("Reverted %d of %d writes.\n", reverted, len(run.Records))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Run %s marked undone.\n", run.Run)
+	return nil
+}
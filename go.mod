@@ -5,9 +5,11 @@ go 1.25.5
 require golang.org/x/time v0.14.0
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/google/uuid v1.6.0
 	github.com/lithammer/fuzzysearch v1.1.8
 	github.com/spf13/cobra v1.10.2
+	golang.org/x/term v0.36.0
 	golang.org/x/text v0.32.0
 	gopkg.in/ini.v1 v1.67.0
 )
@@ -16,4 +18,5 @@ require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.10 // indirect
 	github.com/stretchr/testify v1.11.1 // indirect
+	golang.org/x/sys v0.37.0 // indirect
 )
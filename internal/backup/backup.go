@@ -0,0 +1,197 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+	"github.com/Another0Noob/mangadex-import/internal/match"
+)
+
+// Version is the backup file format version.
+const Version = 1
+
+// Backup is a full snapshot of a MangaDex account's library.
+type Backup struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Manga     []Entry   `json:"manga"`
+	Lists     []List    `json:"lists"`
+}
+
+// Entry is the library state of a single manga.
+type Entry struct {
+	ID              string                    `json:"id"`
+	Title           string                    `json:"title,omitempty"`
+	Followed        bool                      `json:"followed"`
+	Status          mangadexapi.ReadingStatus `json:"status,omitempty"`
+	Rating          int                       `json:"rating,omitempty"`
+	ReadChapters    []string                  `json:"read_chapters,omitempty"` // chapter IDs
+	LastReadChapter string                    `json:"last_read_chapter,omitempty"`
+}
+
+// List is a custom list and its manga IDs.
+type List struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Visibility string   `json:"visibility"`
+	Manga      []string `json:"manga"`
+}
+
+// Create captures follows, reading statuses, ratings, custom lists and read
+// chapters of the authenticated account. Manga that are only in a list or
+// only have a status are included too.
+func Create(ctx context.Context, client *mangadexapi.Client) (*Backup, error) {
+	followed, err := client.GetAllFollowed(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get follows: %w", err)
+	}
+	statuses, err := client.GetMangaStatusList(ctx, mangadexapi.QueryParams{})
+	if err != nil {
+		return nil, fmt.Errorf("get statuses: %w", err)
+	}
+	lists, err := client.GetAllUserLists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get lists: %w", err)
+	}
+
+	entries := make(map[string]*Entry)
+	get := func(id string) *Entry {
+		e, ok := entries[id]
+		if !ok {
+			e = &Entry{ID: id}
+			entries[id] = e
+		}
+		return e
+	}
+
+	for _, m := range followed {
+		e := get(m.ID)
+		e.Followed = true
+		e.Title = match.DisplayTitle(m)
+	}
+	for id, status := range statuses {
+		get(id).Status = status
+	}
+
+	b := &Backup{Version: Version, CreatedAt: time.Now().UTC()}
+	for _, l := range lists {
+		ids := l.MangaIDs()
+		for _, id := range ids {
+			get(id)
+		}
+		b.Lists = append(b.Lists, List{
+			ID:         l.ID,
+			Name:       l.Attributes.Name,
+			Visibility: l.Attributes.Visibility,
+			Manga:      ids,
+		})
+	}
+
+	ids := make([]string, 0, len(entries))
+	var untitled []string
+	for id, e := range entries {
+		ids = append(ids, id)
+		if e.Title == "" {
+			untitled = append(untitled, id)
+		}
+	}
+	sort.Strings(ids)
+
+	if len(untitled) > 0 {
+		manga, err := client.GetMangaByIDs(ctx, untitled)
+		if err != nil {
+			return nil, fmt.Errorf("get titles: %w", err)
+		}
+		for _, m := range manga {
+			if e, ok := entries[m.ID]; ok {
+				e.Title = match.DisplayTitle(m)
+			}
+		}
+	}
+
+	ratings, err := client.GetMangaRatings(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("get ratings: %w", err)
+	}
+	read, err := client.GetReadChapters(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("get read chapters: %w", err)
+	}
+
+	var chapterIDs []string
+	for id, e := range entries {
+		e.Rating = ratings[id].Rating
+		e.ReadChapters = read[id]
+		chapterIDs = append(chapterIDs, read[id]...)
+	}
+
+	chapters, err := client.GetChapters(ctx, chapterIDs)
+	if err != nil {
+		return nil, fmt.Errorf("get chapters: %w", err)
+	}
+	lastRead := make(map[string]float64)
+	for _, ch := range chapters {
+		n, err := strconv.ParseFloat(ch.Attributes.Chapter, 64)
+		if err != nil {
+			continue
+		}
+		e, ok := entries[ch.MangaID()]
+		if !ok {
+			continue
+		}
+		if prev, seen := lastRead[e.ID]; !seen || n > prev {
+			lastRead[e.ID] = n
+			e.LastReadChapter = ch.Attributes.Chapter
+		}
+	}
+
+	b.Manga = make([]Entry, len(ids))
+	for i, id := range ids {
+		b.Manga[i] = *entries[id]
+	}
+	return b, nil
+}
+
+// Write encodes the backup as indented JSON.
+func (b *Backup) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// WriteFile writes the backup to path.
+func (b *Backup) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+	defer file.Close()
+
+	if err := b.Write(file); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Load reads a backup written by WriteFile.
+func Load(path string) (*Backup, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read backup: %w", err)
+	}
+	var b Backup
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("decode backup: %w", err)
+	}
+	if b.Version != Version {
+		return nil, fmt.Errorf("unsupported backup version %d (want %d)", b.Version, Version)
+	}
+	return &b, nil
+}
@@ -0,0 +1,195 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+)
+
+// Change kinds.
+const (
+	ChangeFollow     = "follow"
+	ChangeStatus     = "status"
+	ChangeRating     = "rating"
+	ChangeCreateList = "create list"
+	ChangeAddToList  = "add to list"
+)
+
+// Change is a single write needed to bring the account in line with a backup.
+type Change struct {
+	Kind       string
+	MangaID    string
+	Title      string
+	From       string // current value, empty when unset
+	To         string
+	ListID     string   // target list for ChangeAddToList
+	ListName   string   // for ChangeCreateList and ChangeAddToList
+	Visibility string   // for ChangeCreateList
+	Manga      []string // initial manga for ChangeCreateList
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeFollow:
+		return fmt.Sprintf("follow %s %s", c.MangaID, c.Title)
+	case ChangeStatus, ChangeRating:
+		from := c.From
+		if from == "" {
+			from = "none"
+		}
+		return fmt.Sprintf("%s %s -> %s: %s %s", c.Kind, from, c.To, c.MangaID, c.Title)
+	case ChangeCreateList:
+		return fmt.Sprintf("create list %q with %d manga", c.ListName, len(c.Manga))
+	case ChangeAddToList:
+		return fmt.Sprintf("add %s %s to list %q", c.MangaID, c.Title, c.ListName)
+	default:
+		return c.Kind
+	}
+}
+
+// Diff compares a backup with the current state of the authenticated account
+// and returns the changes needed to restore it. Restoring only adds: follows,
+// statuses, ratings and list entries missing from the backup are left alone.
+// Lists are matched by ID first (same account), then by name.
+func Diff(ctx context.Context, client *mangadexapi.Client, b *Backup) ([]Change, error) {
+	followed, err := client.GetAllFollowed(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get follows: %w", err)
+	}
+	statuses, err := client.GetMangaStatusList(ctx, mangadexapi.QueryParams{})
+	if err != nil {
+		return nil, fmt.Errorf("get statuses: %w", err)
+	}
+	lists, err := client.GetAllUserLists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get lists: %w", err)
+	}
+
+	ids := make([]string, len(b.Manga))
+	for i, e := range b.Manga {
+		ids[i] = e.ID
+	}
+	ratings, err := client.GetMangaRatings(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("get ratings: %w", err)
+	}
+
+	isFollowed := make(map[string]struct{}, len(followed))
+	for _, m := range followed {
+		isFollowed[m.ID] = struct{}{}
+	}
+
+	var changes []Change
+	titles := make(map[string]string, len(b.Manga))
+	for _, e := range b.Manga {
+		titles[e.ID] = e.Title
+		if _, ok := isFollowed[e.ID]; e.Followed && !ok {
+			changes = append(changes, Change{Kind: ChangeFollow, MangaID: e.ID, Title: e.Title})
+		}
+		if e.Status != "" && statuses[e.ID] != e.Status {
+			changes = append(changes, Change{
+				Kind:    ChangeStatus,
+				MangaID: e.ID,
+				Title:   e.Title,
+				From:    string(statuses[e.ID]),
+				To:      string(e.Status),
+			})
+		}
+		if e.Rating > 0 && ratings[e.ID].Rating != e.Rating {
+			from := ""
+			if r := ratings[e.ID].Rating; r > 0 {
+				from = strconv.Itoa(r)
+			}
+			changes = append(changes, Change{
+				Kind:    ChangeRating,
+				MangaID: e.ID,
+				Title:   e.Title,
+				From:    from,
+				To:      strconv.Itoa(e.Rating),
+			})
+		}
+	}
+
+	byID := make(map[string]mangadexapi.CustomList, len(lists))
+	byName := make(map[string]mangadexapi.CustomList, len(lists))
+	for _, l := range lists {
+		byID[l.ID] = l
+		if _, dup := byName[l.Attributes.Name]; !dup {
+			byName[l.Attributes.Name] = l
+		}
+	}
+
+	for _, bl := range b.Lists {
+		current, ok := byID[bl.ID]
+		if !ok {
+			current, ok = byName[bl.Name]
+		}
+		if !ok {
+			changes = append(changes, Change{
+				Kind:       ChangeCreateList,
+				ListName:   bl.Name,
+				Visibility: bl.Visibility,
+				Manga:      bl.Manga,
+			})
+			continue
+		}
+
+		members := make(map[string]struct{})
+		for _, id := range current.MangaIDs() {
+			members[id] = struct{}{}
+		}
+		for _, id := range bl.Manga {
+			if _, ok := members[id]; ok {
+				continue
+			}
+			changes = append(changes, Change{
+				Kind:     ChangeAddToList,
+				MangaID:  id,
+				Title:    titles[id],
+				ListID:   current.ID,
+				ListName: current.Attributes.Name,
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// Apply performs the changes in order. progress, if non-nil, is called after
+// each change with its error; Apply stops at the first error.
+func Apply(ctx context.Context, client *mangadexapi.Client, changes []Change, progress func(Change, error)) error {
+	for _, c := range changes {
+		err := applyChange(ctx, client, c)
+		if progress != nil {
+			progress(c, err)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", c, err)
+		}
+	}
+	return nil
+}
+
+func applyChange(ctx context.Context, client *mangadexapi.Client, c Change) error {
+	switch c.Kind {
+	case ChangeFollow:
+		return client.FollowManga(ctx, c.MangaID)
+	case ChangeStatus:
+		return client.UpdateMangaStatus(ctx, c.MangaID, mangadexapi.ReadingStatus(c.To))
+	case ChangeRating:
+		rating, err := strconv.Atoi(c.To)
+		if err != nil {
+			return err
+		}
+		return client.SetMangaRating(ctx, c.MangaID, rating)
+	case ChangeCreateList:
+		_, err := client.CreateList(ctx, c.ListName, c.Visibility, c.Manga)
+		return err
+	case ChangeAddToList:
+		return client.AddMangaToList(ctx, c.MangaID, c.ListID)
+	default:
+		return fmt.Errorf("unknown change %q", c.Kind)
+	}
+}
@@ -0,0 +1,198 @@
+// Package checkpoint persists the search stage of an import run so that a
+// rerun with the same input and account resumes where it left off.
+package checkpoint
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Another0Noob/mangadex-import/internal/match"
+)
+
+const ext = ".jsonl"
+
+// Key identifies a run by its input file content and MangaDex account.
+func Key(input []byte, account string) string {
+	h := sha256.New()
+	h.Write(input)
+	h.Write([]byte{0})
+	h.Write([]byte(account))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// DefaultDir returns the checkpoint directory under the user cache dir.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mangadex-import", "checkpoints"), nil
+}
+
+type line struct {
+	Entry string            `json:"entry"`
+	State match.SearchState `json:"state"`
+}
+
+// Store is a match.Checkpoint backed by an append-only JSON lines file. It is
+// safe for concurrent use.
+type Store struct {
+	path string
+
+	mu     sync.Mutex
+	file   *os.File
+	states map[string]match.SearchState
+}
+
+var _ match.Checkpoint = (*Store)(nil)
+
+// Open opens the checkpoint for key in dir. With resume set, the states
+// saved by a previous run are loaded; otherwise any old checkpoint is
+// discarded.
+func Open(dir, key string, resume bool) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create checkpoint dir: %w", err)
+	}
+	s := &Store{
+		path:   filepath.Join(dir, key+ext),
+		states: make(map[string]match.SearchState),
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if resume {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+	} else {
+		flags |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(s.path, flags, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open checkpoint: %w", err)
+	}
+	s.file = f
+	return s, nil
+}
+
+func (s *Store) load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open checkpoint: %w", err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		var l line
+		if err := json.Unmarshal(sc.Bytes(), &l); err != nil {
+			// A crash can leave a partial last line; ignore it.
+			continue
+		}
+		s.states[l.Entry] = l.State
+	}
+	return sc.Err()
+}
+
+// Path returns the checkpoint file path.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Len returns the number of entries with a saved state.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.states)
+}
+
+// Lookup implements match.Checkpoint.
+func (s *Store) Lookup(e match.ImportEntry) (match.SearchState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.states[entryKey(e)]
+	return st, ok
+}
+
+// Save implements match.Checkpoint.
+func (s *Store) Save(e match.ImportEntry, st match.SearchState) error {
+	k := entryKey(e)
+	data, err := json.Marshal(line{Entry: k, State: st})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return errors.New("checkpoint closed")
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	s.states[k] = st
+	return nil
+}
+
+// Close closes the file, keeping the checkpoint for a later resume.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// Remove closes and deletes the checkpoint once the run has completed.
+func (s *Store) Remove() error {
+	if err := s.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// entryKey identifies an import entry within its input file.
+func entryKey(e match.ImportEntry) string {
+	row := 0
+	if len(e.Source.Rows) > 0 {
+		row = e.Source.Rows[0]
+	}
+	return strconv.Itoa(row) + ":" + e.Normalized
+}
+
+// CleanupStale removes checkpoints not written to for longer than maxAge.
+func CleanupStale(dir string, maxAge time.Duration) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		if time.Since(info.ModTime()) > maxAge {
+			os.Remove(p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,383 @@
+// Package credentials resolves MangaDex login credentials from a chain of
+// sources: command line flags, environment variables, an auth file and an
+// interactive prompt.
+package credentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/ini.v1"
+)
+
+// Credential fields, as named in auth files.
+const (
+	FieldUsername     = "username"
+	FieldPassword     = "password"
+	FieldClientID     = "client_id"
+	FieldClientSecret = "client_secret"
+)
+
+var fields = []string{FieldUsername, FieldPassword, FieldClientID, FieldClientSecret}
+
+// Environment variables read by Env.
+const (
+	EnvUsername         = "MANGADEX_USERNAME"
+	EnvPassword         = "MANGADEX_PASSWORD"
+	EnvPasswordFile     = "MANGADEX_PASSWORD_FILE"
+	EnvClientID         = "MANGADEX_CLIENT_ID"
+	EnvClientSecret     = "MANGADEX_CLIENT_SECRET"
+	EnvClientSecretFile = "MANGADEX_CLIENT_SECRET_FILE"
+)
+
+// Values holds credential fields by name. Missing fields are empty.
+type Values map[string]string
+
+// Source provides some of the credential fields.
+type Source interface {
+	// Name describes the source in messages, e.g. "environment".
+	Name() string
+	// Fill sets fields of v that are still empty.
+	Fill(v Values) error
+}
+
+// Resolved are credentials together with the source of each field.
+type Resolved struct {
+	Form    mangadexapi.AuthForm
+	Origins map[string]string // field -> source name
+}
+
+// String lists which source provided which fields.
+func (r Resolved) String() string {
+	var order []string
+	bySource := make(map[string][]string)
+	for _, f := range fields {
+		src := r.Origins[f]
+		if _, ok := bySource[src]; !ok {
+			order = append(order, src)
+		}
+		bySource[src] = append(bySource[src], f)
+	}
+	parts := make([]string, len(order))
+	for i, src := range order {
+		parts[i] = strings.Join(bySource[src], ", ") + " from " + src
+	}
+	return strings.Join(parts, "; ")
+}
+
+// waiver is a source that makes fields unnecessary instead of filling them.
+type waiver interface {
+	waived(v Values) []string
+}
+
+// Resolve asks the sources in order of precedence; a field set by one source
+// is not overridden by later ones. It fails naming the missing fields and the
+// sources that were checked.
+func Resolve(sources ...Source) (*Resolved, error) {
+	v := make(Values)
+	origins := make(map[string]string)
+	waived := make(map[string]bool)
+	var checked []string
+	for _, s := range sources {
+		if s == nil {
+			continue
+		}
+		checked = append(checked, s.Name())
+		before := make(Values, len(v))
+		for k, val := range v {
+			before[k] = val
+		}
+		var err error
+		if p, ok := s.(prompt); ok {
+			err = p.fill(v, waived)
+		} else {
+			err = s.Fill(v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Name(), err)
+		}
+		for _, f := range fields {
+			if before[f] == "" && v[f] != "" {
+				origins[f] = s.Name()
+			}
+		}
+		if w, ok := s.(waiver); ok {
+			for _, f := range w.waived(v) {
+				if v[f] == "" && !waived[f] {
+					waived[f] = true
+					origins[f] = s.Name()
+				}
+			}
+		}
+	}
+
+	var missing []string
+	for _, f := range fields {
+		if v[f] == "" && !waived[f] {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing %s (checked %s)", strings.Join(missing, ", "), strings.Join(checked, ", "))
+	}
+
+	return &Resolved{
+		Form: mangadexapi.AuthForm{
+			Username:     v[FieldUsername],
+			Password:     v[FieldPassword],
+			ClientID:     v[FieldClientID],
+			ClientSecret: v[FieldClientSecret],
+		},
+		Origins: origins,
+	}, nil
+}
+
+// setMissing sets v[field] if it is empty.
+func setMissing(v Values, field, val string) {
+	if v[field] == "" && val != "" {
+		v[field] = val
+	}
+}
+
+// readSecretFile reads a secret from a file such as a Docker or Kubernetes
+// secret, without the trailing newline.
+func readSecretFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+type static struct {
+	name string
+	v    Values
+}
+
+// Static is a source of fixed values, e.g. from command line flags.
+func Static(name string, v Values) Source {
+	return static{name: name, v: v}
+}
+
+func (s static) Name() string { return s.name }
+
+func (s static) Fill(v Values) error {
+	for _, f := range fields {
+		setMissing(v, f, s.v[f])
+	}
+	return nil
+}
+
+type secretFile struct {
+	name, field, path string
+}
+
+// SecretFile reads one field, such as the password, from a file. An empty
+// path provides nothing.
+func SecretFile(name, field, path string) Source {
+	return secretFile{name: name, field: field, path: path}
+}
+
+func (s secretFile) Name() string { return s.name }
+
+func (s secretFile) Fill(v Values) error {
+	if s.path == "" || v[s.field] != "" {
+		return nil
+	}
+	secret, err := readSecretFile(s.path)
+	if err != nil {
+		return err
+	}
+	setMissing(v, s.field, secret)
+	return nil
+}
+
+type env struct{}
+
+// Env reads the MANGADEX_* environment variables. The *_FILE variables name
+// files holding the password or client secret.
+func Env() Source {
+	return env{}
+}
+
+func (env) Name() string { return "environment" }
+
+func (env) Fill(v Values) error {
+	setMissing(v, FieldUsername, os.Getenv(EnvUsername))
+	setMissing(v, FieldPassword, os.Getenv(EnvPassword))
+	setMissing(v, FieldClientID, os.Getenv(EnvClientID))
+	setMissing(v, FieldClientSecret, os.Getenv(EnvClientSecret))
+	for field, name := range map[string]string{FieldPassword: EnvPasswordFile, FieldClientSecret: EnvClientSecretFile} {
+		path := os.Getenv(name)
+		if v[field] != "" || path == "" {
+			continue
+		}
+		secret, err := readSecretFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		setMissing(v, field, secret)
+	}
+	return nil
+}
+
+type file struct {
+	path string
+}
+
+// File reads an auth file. The format follows the extension: .json, .toml,
+// or INI otherwise. Fields are read from a "mangadex" section or table, or
+// from the top level of JSON and TOML files. password_file and
+// client_secret_file name secret files, relative to the auth file.
+func File(path string) Source {
+	return file{path: path}
+}
+
+func (f file) Name() string { return f.path }
+
+func (f file) Fill(v Values) error {
+	raw, err := f.read()
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		setMissing(v, field, raw[field])
+	}
+	for field, key := range map[string]string{FieldPassword: "password_file", FieldClientSecret: "client_secret_file"} {
+		path := raw[key]
+		if v[field] != "" || path == "" {
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(f.path), path)
+		}
+		secret, err := readSecretFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		setMissing(v, field, secret)
+	}
+	return nil
+}
+
+// read returns the string values of the auth file.
+func (f file) read() (map[string]string, error) {
+	switch strings.ToLower(filepath.Ext(f.path)) {
+	case ".json":
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			return nil, err
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parse JSON: %w", err)
+		}
+		return section(doc), nil
+	case ".toml":
+		var doc map[string]any
+		if _, err := toml.DecodeFile(f.path, &doc); err != nil {
+			return nil, fmt.Errorf("parse TOML: %w", err)
+		}
+		return section(doc), nil
+	default:
+		cfg, err := ini.Load(f.path)
+		if err != nil {
+			return nil, err
+		}
+		out := make(map[string]string)
+		for _, k := range cfg.Section("mangadex").Keys() {
+			out[k.Name()] = k.String()
+		}
+		return out, nil
+	}
+}
+
+// section returns the string values of the "mangadex" table, or of the
+// top level if there is none.
+func section(doc map[string]any) map[string]string {
+	if sub, ok := doc["mangadex"].(map[string]any); ok {
+		doc = sub
+	}
+	out := make(map[string]string)
+	for k, val := range doc {
+		if s, ok := val.(string); ok {
+			out[k] = s
+		}
+	}
+	return out
+}
+
+type savedToken struct {
+	has func(username, clientID string) bool
+}
+
+// SavedToken makes the password optional when has reports a saved token for
+// the username and client ID resolved so far, as with
+// mangadexapi.Client.HasSavedToken. Put it before Prompt so that the
+// password is not asked for.
+func SavedToken(has func(username, clientID string) bool) Source {
+	return savedToken{has: has}
+}
+
+func (savedToken) Name() string { return "saved token" }
+
+func (savedToken) Fill(v Values) error { return nil }
+
+func (s savedToken) waived(v Values) []string {
+	if v[FieldPassword] != "" || v[FieldUsername] == "" || v[FieldClientID] == "" {
+		return nil
+	}
+	if !s.has(v[FieldUsername], v[FieldClientID]) {
+		return nil
+	}
+	return []string{FieldPassword}
+}
+
+type prompt struct {
+	in  *os.File
+	out io.Writer
+}
+
+// Prompt asks for the fields still missing on the terminal in, without
+// echoing secrets. It does nothing when in is not a terminal, so that the
+// missing fields are reported instead.
+func Prompt(in *os.File, out io.Writer) Source {
+	return prompt{in: in, out: out}
+}
+
+func (prompt) Name() string { return "prompt" }
+
+func (p prompt) Fill(v Values) error {
+	return p.fill(v, nil)
+}
+
+// fill prompts for the missing fields that are not waived.
+func (p prompt) fill(v Values, waived map[string]bool) error {
+	if !isTerminal(p.in) {
+		return nil
+	}
+	labels := map[string]string{
+		FieldUsername:     "MangaDex username",
+		FieldPassword:     "MangaDex password",
+		FieldClientID:     "API client ID",
+		FieldClientSecret: "API client secret",
+	}
+	for _, f := range fields {
+		if v[f] != "" || waived[f] {
+			continue
+		}
+		secret := f == FieldPassword || f == FieldClientSecret
+		val, err := readLine(p.in, p.out, labels[f]+": ", secret)
+		if err != nil {
+			return err
+		}
+		setMissing(v, f, val)
+	}
+	return nil
+}
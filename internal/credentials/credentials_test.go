@@ -0,0 +1,68 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	authPath := filepath.Join(dir, "auth.toml")
+	auth := "[mangadex]\nusername = \"file-user\"\npassword_file = \"secret\"\nclient_id = \"file-id\"\n"
+	if err := os.WriteFile(authPath, []byte(auth), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "secret"), []byte("hunter2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvClientID, "env-id")
+	t.Setenv(EnvClientSecret, "env-secret")
+
+	res, err := Resolve(
+		Static("flags", Values{FieldUsername: "flag-user"}),
+		Env(),
+		File(authPath),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := res.Form
+	if f.Username != "flag-user" || f.Password != "hunter2" || f.ClientID != "env-id" || f.ClientSecret != "env-secret" {
+		t.Errorf("form = %+v", f)
+	}
+	if res.Origins[FieldPassword] != authPath || res.Origins[FieldUsername] != "flags" {
+		t.Errorf("origins = %v", res.Origins)
+	}
+}
+
+func TestResolveMissing(t *testing.T) {
+	t.Setenv(EnvClientID, "")
+	t.Setenv(EnvClientSecret, "")
+	t.Setenv(EnvClientSecretFile, "")
+	_, err := Resolve(Static("flags", Values{FieldUsername: "u", FieldPassword: "p"}), Env())
+	if err == nil || !strings.Contains(err.Error(), "missing client_id, client_secret (checked flags, environment)") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestResolveSavedToken(t *testing.T) {
+	t.Setenv(EnvPassword, "")
+	t.Setenv(EnvPasswordFile, "")
+	flags := Static("flags", Values{FieldUsername: "u", FieldClientID: "id", FieldClientSecret: "s"})
+	has := func(username, clientID string) bool { return username == "u" && clientID == "id" }
+
+	res, err := Resolve(flags, Env(), SavedToken(has))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Form.Password != "" || res.Origins[FieldPassword] != "saved token" {
+		t.Errorf("form = %+v, origins = %v", res.Form, res.Origins)
+	}
+
+	none := func(string, string) bool { return false }
+	if _, err := Resolve(flags, Env(), SavedToken(none)); err == nil || !strings.Contains(err.Error(), "missing password") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package credentials
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+func isTerminal(f *os.File) bool {
+	return f != nil && term.IsTerminal(int(f.Fd()))
+}
+
+// readLine prints label and reads a line from the terminal in, without echo
+// if secret is set.
+func readLine(in *os.File, out io.Writer, label string, secret bool) (string, error) {
+	fmt.Fprint(out, label)
+	if secret {
+		b, err := term.ReadPassword(int(in.Fd()))
+		fmt.Fprintln(out)
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", strings.TrimSuffix(label, ": "), err)
+		}
+		return string(b), nil
+	}
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("read %s: %w", strings.TrimSuffix(label, ": "), err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,189 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+	"github.com/Another0Noob/mangadex-import/internal/mangaparser"
+	"github.com/Another0Noob/mangadex-import/internal/match"
+)
+
+// Format selects the export output.
+type Format string
+
+const (
+	FormatMAL    Format = "mal"    // MAL-importable XML
+	FormatComick Format = "comick" // Comick-style CSV
+	FormatJSON   Format = "json"   // titles, statuses, ratings and read chapters
+	FormatURLs   Format = "urls"   // one mangadex.org URL per line
+)
+
+// ParseFormat validates a --format value.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatMAL, FormatComick, FormatJSON, FormatURLs:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown export format: %s (must be mal, comick, json or urls)", s)
+	}
+}
+
+// Extension returns the file extension used for f.
+func (f Format) Extension() string {
+	switch f {
+	case FormatMAL:
+		return ".xml"
+	case FormatComick:
+		return ".csv"
+	case FormatJSON:
+		return ".json"
+	default:
+		return ".txt"
+	}
+}
+
+// Item is a followed manga with the user's library data.
+type Item struct {
+	ID           string                    `json:"id"`
+	Title        string                    `json:"title"`
+	AltTitles    []string                  `json:"alt_titles,omitempty"`
+	URL          string                    `json:"url"`
+	Status       mangadexapi.ReadingStatus `json:"status,omitempty"`
+	Rating       int                       `json:"rating,omitempty"`
+	ReadChapters int                       `json:"read_chapters,omitempty"`
+	Links        map[string]string         `json:"links,omitempty"`
+}
+
+// Collect fetches the user's follows and, unless only URLs are needed, their
+// reading statuses, ratings and read chapter counts.
+func Collect(ctx context.Context, client *mangadexapi.Client, format Format) ([]Item, error) {
+	followed, err := client.GetAllFollowed(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get follows: %w", err)
+	}
+
+	items := make([]Item, len(followed))
+	ids := make([]string, len(followed))
+	for i, m := range followed {
+		ids[i] = m.ID
+		items[i] = Item{
+			ID:        m.ID,
+			Title:     match.DisplayTitle(m),
+			AltTitles: altTitles(m),
+			URL:       m.URL(),
+			Links:     m.Attributes.Links,
+		}
+	}
+	if format == FormatURLs || len(items) == 0 {
+		return items, nil
+	}
+
+	statuses, err := client.GetMangaStatusList(ctx, mangadexapi.QueryParams{})
+	if err != nil {
+		return nil, fmt.Errorf("get statuses: %w", err)
+	}
+	ratings, err := client.GetMangaRatings(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("get ratings: %w", err)
+	}
+	read, err := client.GetReadChapters(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("get read chapters: %w", err)
+	}
+
+	for i := range items {
+		id := items[i].ID
+		items[i].Status = statuses[id]
+		items[i].Rating = ratings[id].Rating
+		items[i].ReadChapters = len(read[id])
+	}
+	return items, nil
+}
+
+// Write writes items in the given format.
+func Write(w io.Writer, format Format, items []Item) error {
+	switch format {
+	case FormatMAL:
+		return mangaparser.Write(w, mangaparser.FormatMAL, toEntries(items))
+	case FormatComick:
+		return mangaparser.Write(w, mangaparser.FormatComick, toEntries(items))
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	case FormatURLs:
+		for _, it := range items {
+			if _, err := fmt.Fprintln(w, it.URL); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown export format: %s", format)
+	}
+}
+
+// WriteFile writes items to path, creating parent directories as needed.
+func WriteFile(path string, format Format, items []Item) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create output dir: %w", err)
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+	defer file.Close()
+
+	if err := Write(file, format, items); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// linkSites maps MangaDex link keys to mangaparser external ID prefixes.
+var linkSites = map[string]string{
+	"mal": "mal",
+	"al":  "anilist",
+	"mu":  "mu",
+}
+
+func toEntries(items []Item) []mangaparser.Entry {
+	entries := make([]mangaparser.Entry, len(items))
+	for i, it := range items {
+		e := mangaparser.Entry{
+			Title:    it.Title,
+			Synonyms: it.AltTitles,
+			Status:   mangaparser.Status(it.Status),
+			Progress: float64(it.ReadChapters),
+			Rating:   it.Rating,
+		}
+		for key, site := range linkSites {
+			if v := it.Links[key]; v != "" {
+				e.ExternalIDs = append(e.ExternalIDs, site+":"+v)
+			}
+		}
+		entries[i] = e
+	}
+	return entries
+}
+
+func altTitles(m mangadexapi.Manga) []string {
+	var out []string
+	for _, alt := range m.Attributes.AltTitles {
+		for lang, t := range alt {
+			if (lang == "en" || strings.HasSuffix(lang, "-ro")) && t != "" {
+				out = append(out, t)
+			}
+		}
+	}
+	return out
+}
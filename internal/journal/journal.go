@@ -0,0 +1,354 @@
+// Package journal records every follow, status and rating write together
+// with the state it replaced, so a run can be undone.
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+)
+
+const (
+	ext       = ".jsonl"
+	undoneExt = ".undone" + ext
+)
+
+// Header is the first line of a journal file.
+type Header struct {
+	Run      string    `json:"run"`
+	Command  string    `json:"command"`
+	Username string    `json:"username,omitempty"` // account the writes were made on
+	Started  time.Time `json:"started"`
+}
+
+// Record is a single successful write and the state before it.
+type Record struct {
+	Time         time.Time                 `json:"time"`
+	Kind         string                    `json:"kind"` // one of the mangadexapi.Write* kinds
+	MangaID      string                    `json:"manga_id"`
+	Status       mangadexapi.ReadingStatus `json:"status,omitempty"`
+	Rating       int                       `json:"rating,omitempty"`
+	PrevFollowed bool                      `json:"prev_followed,omitempty"`
+	PrevStatus   mangadexapi.ReadingStatus `json:"prev_status,omitempty"`
+	PrevRating   int                       `json:"prev_rating,omitempty"`
+}
+
+func (r Record) String() string {
+	switch r.Kind {
+	case mangadexapi.WriteStatus:
+		return fmt.Sprintf("status %s -> %s: %s", orNone(string(r.PrevStatus)), orNone(string(r.Status)), r.MangaID)
+	case mangadexapi.WriteRating, mangadexapi.WriteDeleteRating:
+		return fmt.Sprintf("rating %d -> %d: %s", r.PrevRating, r.Rating, r.MangaID)
+	default:
+		return fmt.Sprintf("%s %s", r.Kind, r.MangaID)
+	}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// DefaultDir returns the journal directory under the user config dir.
+func DefaultDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mangadex-import", "journal"), nil
+}
+
+// Journal appends the writes of one run to <dir>/<run>.jsonl. The file is
+// only created on the first write.
+type Journal struct {
+	Header
+
+	client *mangadexapi.Client
+	dir    string
+	path   string
+
+	mu   sync.Mutex
+	file *os.File
+	n    int
+}
+
+// Start creates a journal for a new run on the client's account and installs
+// it as the client's write hook.
+func Start(dir, command string, client *mangadexapi.Client) *Journal {
+	now := time.Now()
+	run := now.Format("20060102-150405")
+	j := &Journal{
+		Header: Header{Run: run, Command: command, Username: client.Username(), Started: now},
+		client: client,
+		dir:    dir,
+		path:   filepath.Join(dir, run+ext),
+	}
+	client.SetWriteHook(j.hook)
+	return j
+}
+
+// Path returns the journal file path.
+func (j *Journal) Path() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.path
+}
+
+// Len returns the number of recorded writes.
+func (j *Journal) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.n
+}
+
+// Close removes the write hook and closes the file.
+func (j *Journal) Close() error {
+	j.client.SetWriteHook(nil)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+func (j *Journal) hook(ctx context.Context, op mangadexapi.WriteOp, write func() error) error {
+	rec, err := j.prior(ctx, op)
+	if err != nil {
+		return fmt.Errorf("journal: read current state of %s: %w", op.MangaID, err)
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	rec.Time = time.Now()
+	if err := j.append(rec); err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+	return nil
+}
+
+// prior reads the state the write is about to replace.
+func (j *Journal) prior(ctx context.Context, op mangadexapi.WriteOp) (Record, error) {
+	rec := Record{Kind: op.Kind, MangaID: op.MangaID, Status: op.Status, Rating: op.Rating}
+	switch op.Kind {
+	case mangadexapi.WriteFollow, mangadexapi.WriteUnfollow:
+		followed, err := j.client.CheckFollowedManga(ctx, op.MangaID, mangadexapi.QueryParams{})
+		if err != nil {
+			return rec, err
+		}
+		rec.PrevFollowed = followed
+	case mangadexapi.WriteStatus:
+		status, err := j.client.GetMangaStatus(ctx, op.MangaID)
+		if err != nil && !errors.Is(err, mangadexapi.ErrNotFound) {
+			return rec, err
+		}
+		if status != nil {
+			rec.PrevStatus = *status
+		}
+	case mangadexapi.WriteRating, mangadexapi.WriteDeleteRating:
+		ratings, err := j.client.GetMangaRatings(ctx, []string{op.MangaID})
+		if err != nil {
+			return rec, err
+		}
+		rec.PrevRating = ratings[op.MangaID].Rating
+	}
+	return rec, nil
+}
+
+func (j *Journal) append(rec Record) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		f, err := j.create()
+		if err != nil {
+			return err
+		}
+		j.file = f
+		if err := json.NewEncoder(f).Encode(j.Header); err != nil {
+			return err
+		}
+	}
+	if err := json.NewEncoder(j.file).Encode(rec); err != nil {
+		return err
+	}
+	j.n++
+	return nil
+}
+
+// create creates the journal file. A run started within the same second as
+// an earlier one gets a -N suffix; O_EXCL keeps concurrent runs from sharing
+// a file. j.mu must be held.
+func (j *Journal) create() (*os.File, error) {
+	if err := os.MkdirAll(j.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create journal dir: %w", err)
+	}
+	base := j.Started.Format("20060102-150405")
+	for n := 1; ; n++ {
+		run := base
+		if n > 1 {
+			run = fmt.Sprintf("%s-%d", base, n)
+		}
+		if _, err := os.Stat(filepath.Join(j.dir, run+undoneExt)); err == nil {
+			continue
+		}
+		path := filepath.Join(j.dir, run+ext)
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("open journal: %w", err)
+		}
+		j.Run, j.path = run, path
+		return f, nil
+	}
+}
+
+// Run is a journal file read back from disk.
+type Run struct {
+	Header
+	Path    string
+	Undone  bool
+	Records []Record
+}
+
+// List returns all runs in dir, newest first.
+func List(dir string) ([]Run, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]Run, 0, len(paths))
+	for _, p := range paths {
+		r, err := Load(p)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *r)
+	}
+	sort.Slice(runs, func(a, b int) bool { return runs[a].Run > runs[b].Run })
+	return runs, nil
+}
+
+// Find returns the run with the given ID, or the newest run of username not
+// yet undone if id is empty. A run with the given ID made on another account
+// is an error.
+func Find(dir, id, username string) (*Run, error) {
+	runs, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i := range runs {
+		r := &runs[i]
+		switch {
+		case id == "":
+			if !r.Undone && r.Username == username {
+				return r, nil
+			}
+		case r.Run == id:
+			if r.Username != "" && r.Username != username {
+				return nil, fmt.Errorf("run %s was made on account %s, not %s", id, r.Username, username)
+			}
+			return r, nil
+		}
+	}
+	if id == "" {
+		return nil, fmt.Errorf("no run of %s to undo", username)
+	}
+	return nil, fmt.Errorf("run %s not found", id)
+}
+
+// Load reads a journal file.
+func Load(path string) (*Run, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+	r := &Run{Path: path, Undone: strings.HasSuffix(path, undoneExt)}
+	for i, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if i == 0 {
+			if err := json.Unmarshal([]byte(line), &r.Header); err != nil {
+				return nil, fmt.Errorf("decode journal %s: %w", path, err)
+			}
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return nil, fmt.Errorf("decode journal %s line %d: %w", path, i+1, err)
+		}
+		r.Records = append(r.Records, rec)
+	}
+	return r, nil
+}
+
+// Undo reverts the run's writes in reverse order and marks it undone.
+// progress, if non-nil, is called after each record with its error; Undo
+// stops at the first error and leaves the run as is.
+func Undo(ctx context.Context, client *mangadexapi.Client, r *Run, progress func(Record, error)) error {
+	if r.Undone {
+		return fmt.Errorf("run %s already undone", r.Run)
+	}
+	for i := len(r.Records) - 1; i >= 0; i-- {
+		rec := r.Records[i]
+		err := revert(ctx, client, rec)
+		if progress != nil {
+			progress(rec, err)
+		}
+		if err != nil {
+			return fmt.Errorf("undo %s: %w", rec, err)
+		}
+	}
+
+	undone := strings.TrimSuffix(r.Path, ext) + undoneExt
+	if err := os.Rename(r.Path, undone); err != nil {
+		return fmt.Errorf("mark run undone: %w", err)
+	}
+	r.Path, r.Undone = undone, true
+	return nil
+}
+
+func revert(ctx context.Context, client *mangadexapi.Client, rec Record) error {
+	switch rec.Kind {
+	case mangadexapi.WriteFollow:
+		if rec.PrevFollowed {
+			return nil
+		}
+		return client.UnfollowManga(ctx, rec.MangaID)
+	case mangadexapi.WriteUnfollow:
+		if !rec.PrevFollowed {
+			return nil
+		}
+		return client.FollowManga(ctx, rec.MangaID)
+	case mangadexapi.WriteStatus:
+		if rec.PrevStatus == rec.Status {
+			return nil
+		}
+		if rec.PrevStatus == "" {
+			return client.ClearMangaStatus(ctx, rec.MangaID)
+		}
+		return client.UpdateMangaStatus(ctx, rec.MangaID, rec.PrevStatus)
+	case mangadexapi.WriteRating, mangadexapi.WriteDeleteRating:
+		if rec.PrevRating == rec.Rating {
+			return nil
+		}
+		if rec.PrevRating == 0 {
+			return client.DeleteMangaRating(ctx, rec.MangaID)
+		}
+		return client.SetMangaRating(ctx, rec.MangaID, rec.PrevRating)
+	default:
+		return fmt.Errorf("unknown write %q", rec.Kind)
+	}
+}
@@ -0,0 +1,40 @@
+package journal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeRun(t *testing.T, dir string, h Header) {
+	t.Helper()
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, h.Run+ext), append(data, '\n'), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindByAccount(t *testing.T) {
+	dir := t.TempDir()
+	writeRun(t, dir, Header{Run: "20260101-000000", Command: "follow", Username: "alice"})
+	writeRun(t, dir, Header{Run: "20260102-000000", Command: "follow", Username: "bob"})
+
+	r, err := Find(dir, "", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Run != "20260101-000000" {
+		t.Errorf("found run %s for alice, want 20260101-000000", r.Run)
+	}
+
+	if _, err := Find(dir, "20260102-000000", "alice"); err == nil {
+		t.Error("found bob's run for alice")
+	}
+	if _, err := Find(dir, "", "carol"); err == nil {
+		t.Error("found a run for carol")
+	}
+}
@@ -2,23 +2,35 @@ package mangadexapi
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"iter"
 	"net/http"
+	"net/url"
 )
 
+// GetMangaList returns up to qp.Limit manga matching qp, or all of them if
+// qp.Limit is 0, paging through Paginate.
 func (c *Client) GetMangaList(ctx context.Context, qp QueryParams) ([]Manga, error) {
-	params := qp.ToValues()
 	var list []Manga
-	if err := c.EnsureToken(ctx); err != nil {
-		return nil, err
-	}
-	if err := c.doData(ctx, http.MethodGet, "/manga", params, nil, &list); err != nil {
-		return nil, err
+	for m, err := range Paginate(ctx, qp, c.GetMangaPage, mangaWindow) {
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+		if len(list) == qp.Limit {
+			break
+		}
 	}
 	return list, nil
 }
 
+// GetMangaPage returns one page of manga search results.
+func (c *Client) GetMangaPage(ctx context.Context, qp QueryParams) ([]Manga, Stats, error) {
+	return getPage[Manga](ctx, c, "/manga", qp)
+}
+
 func (c *Client) GetManga(ctx context.Context, id string, qp QueryParams) (*Manga, error) {
 	params := qp.ToValues()
 	params.Del("id")
@@ -33,27 +45,14 @@ func (c *Client) GetManga(ctx context.Context, id string, qp QueryParams) (*Mang
 }
 
 func (c *Client) GetFollowedMangaList(ctx context.Context, qp QueryParams) ([]Manga, Stats, error) {
-	params := qp.ToValues()
-	if err := c.EnsureToken(ctx); err != nil {
-		return nil, Stats{}, err
-	}
-	env, _, err := c.doEnvelope(ctx, http.MethodGet, "/user/follows/manga", params, nil)
-	if err != nil {
-		return nil, Stats{}, err
-	}
-	if env == nil || len(env.Data) == 0 { // tolerate empty data
-		return nil, Stats{}, nil
-	}
-	var s Stats
-	s.Limit = *env.Limit
-	s.Offset = *env.Offset
-	s.Total = *env.Total
+	return getPage[Manga](ctx, c, "/user/follows/manga", qp)
+}
 
-	var list []Manga
-	if err := decodeData(env.Data, &list); err != nil {
-		return nil, Stats{}, fmt.Errorf("decode data: %w", err)
-	}
-	return list, s, nil
+// Follows yields the user's followed manga with their cover art, windowed by
+// creation date past the offset cap.
+func (c *Client) Follows(ctx context.Context) iter.Seq2[Manga, error] {
+	qp := QueryParams{Includes: []ReferenceExpansionManga{RefExpCoverArt}}
+	return Paginate(ctx, qp, c.GetFollowedMangaList, mangaWindow)
 }
 
 type Stats struct {
@@ -78,11 +77,13 @@ func (c *Client) CheckFollowedManga(ctx context.Context, id string, qp QueryPara
 	return false, err
 }
 
+// GetMangaStatusList returns the reading status of every manga the user has
+// one for. The endpoint is not paginated.
 func (c *Client) GetMangaStatusList(ctx context.Context, qp QueryParams) (map[string]ReadingStatus, error) {
 	params := qp.ToValues()
 	params.Del("id")
 	var wrapper struct {
-		Statuses map[string]ReadingStatus `json:"statuses"`
+		Statuses json.RawMessage `json:"statuses"`
 	}
 	if err := c.EnsureToken(ctx); err != nil {
 		return nil, err
@@ -90,7 +91,11 @@ func (c *Client) GetMangaStatusList(ctx context.Context, qp QueryParams) (map[st
 	if err := c.doInto(ctx, http.MethodGet, "/manga/status", params, nil, &wrapper); err != nil {
 		return nil, err
 	}
-	return wrapper.Statuses, nil
+	statuses := make(map[string]ReadingStatus)
+	if err := decodeMap(wrapper.Statuses, &statuses); err != nil {
+		return nil, fmt.Errorf("decode statuses: %w", err)
+	}
+	return statuses, nil
 }
 
 func (c *Client) GetMangaStatus(ctx context.Context, id string) (*ReadingStatus, error) {
@@ -110,7 +115,72 @@ func (c *Client) FollowManga(ctx context.Context, id string) error {
 	if err := c.EnsureToken(ctx); err != nil {
 		return err
 	}
-	if err := c.doCheck(ctx, http.MethodPost, "/manga/"+id+"/follow", nil); err != nil {
+	return c.doWrite(ctx, WriteOp{Kind: WriteFollow, MangaID: id}, func() error {
+		return c.doCheck(ctx, http.MethodPost, "/manga/"+id+"/follow", nil)
+	})
+}
+
+// UnfollowManga removes a manga from the user's follows.
+func (c *Client) UnfollowManga(ctx context.Context, id string) error {
+	if err := c.EnsureToken(ctx); err != nil {
+		return err
+	}
+	return c.doWrite(ctx, WriteOp{Kind: WriteUnfollow, MangaID: id}, func() error {
+		return c.doCheck(ctx, http.MethodDelete, "/manga/"+id+"/follow", nil)
+	})
+}
+
+// SetMangaRating rates a manga from 1 to 10.
+func (c *Client) SetMangaRating(ctx context.Context, id string, rating int) error {
+	if rating < 1 || rating > 10 {
+		return fmt.Errorf("rating %d out of range 1-10", rating)
+	}
+	body := struct {
+		Rating int `json:"rating"`
+	}{Rating: rating}
+	var dummy struct{}
+	if err := c.EnsureToken(ctx); err != nil {
+		return err
+	}
+	return c.doWrite(ctx, WriteOp{Kind: WriteRating, MangaID: id, Rating: rating}, func() error {
+		return c.doInto(ctx, http.MethodPost, "/rating/"+id, nil, body, &dummy)
+	})
+}
+
+// DeleteMangaRating removes the user's rating of a manga.
+func (c *Client) DeleteMangaRating(ctx context.Context, id string) error {
+	var dummy struct{}
+	if err := c.EnsureToken(ctx); err != nil {
+		return err
+	}
+	return c.doWrite(ctx, WriteOp{Kind: WriteDeleteRating, MangaID: id}, func() error {
+		return c.doInto(ctx, http.MethodDelete, "/rating/"+id, nil, nil, &dummy)
+	})
+}
+
+// CreateList creates a custom list containing the given manga.
+func (c *Client) CreateList(ctx context.Context, name, visibility string, mangaIDs []string) (*CustomList, error) {
+	body := struct {
+		Name       string   `json:"name"`
+		Visibility string   `json:"visibility,omitempty"`
+		Manga      []string `json:"manga"`
+	}{Name: name, Visibility: visibility, Manga: mangaIDs}
+	var l CustomList
+	if err := c.EnsureToken(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.doData(ctx, http.MethodPost, "/list", nil, body, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// AddMangaToList adds a manga to an existing custom list.
+func (c *Client) AddMangaToList(ctx context.Context, mangaID, listID string) error {
+	if err := c.EnsureToken(ctx); err != nil {
+		return err
+	}
+	if err := c.doCheck(ctx, http.MethodPost, "/manga/"+mangaID+"/list/"+listID, nil); err != nil {
 		return err
 	}
 	return nil
@@ -127,27 +197,148 @@ func (c *Client) UpdateMangaStatus(ctx context.Context, id string, status Readin
 	if err := c.EnsureToken(ctx); err != nil {
 		return err
 	}
-	if err := c.doInto(ctx, http.MethodPost, "/manga/"+id+"/status", nil, body, &dummy); err != nil {
+	return c.doWrite(ctx, WriteOp{Kind: WriteStatus, MangaID: id, Status: status}, func() error {
+		return c.doInto(ctx, http.MethodPost, "/manga/"+id+"/status", nil, body, &dummy)
+	})
+}
+
+// ClearMangaStatus removes the reading status of a manga.
+func (c *Client) ClearMangaStatus(ctx context.Context, id string) error {
+	body := struct {
+		Status *ReadingStatus `json:"status"`
+	}{}
+	var dummy struct{}
+	if err := c.EnsureToken(ctx); err != nil {
 		return err
 	}
-	return nil
+	return c.doWrite(ctx, WriteOp{Kind: WriteStatus, MangaID: id}, func() error {
+		return c.doInto(ctx, http.MethodPost, "/manga/"+id+"/status", nil, body, &dummy)
+	})
 }
 
-func (c *Client) GetAllFollowed(ctx context.Context) ([]Manga, error) {
-	limit := 100
-	offset := 0
-	firstPage, s, err := c.GetFollowedMangaList(ctx, QueryParams{Limit: limit, Offset: offset})
-	followedManga := firstPage
-	for err == nil && len(followedManga) != s.Total {
-		offset += len(firstPage)
-		firstPage, _, err = c.GetFollowedMangaList(ctx, QueryParams{Limit: limit, Offset: offset})
-		if err == nil {
-			followedManga = append(followedManga, firstPage...)
-		}
-	}
-	if err != nil {
-		return nil, err
+// GetMangaRatings returns the user's ratings for the given manga IDs. Manga
+// the user has not rated are absent from the map.
+func (c *Client) GetMangaRatings(ctx context.Context, ids []string) (map[string]Rating, error) {
+	out := make(map[string]Rating, len(ids))
+	for _, batch := range batchIDs(ids, idBatchSize) {
+		params := url.Values{}
+		for _, id := range batch {
+			params.Add("manga[]", id)
+		}
+		var wrapper struct {
+			Ratings json.RawMessage `json:"ratings"`
+		}
+		if err := c.EnsureToken(ctx); err != nil {
+			return nil, err
+		}
+		if err := c.doInto(ctx, http.MethodGet, "/rating", params, nil, &wrapper); err != nil {
+			return nil, err
+		}
+		var ratings map[string]Rating
+		if err := decodeMap(wrapper.Ratings, &ratings); err != nil {
+			return nil, fmt.Errorf("decode ratings: %w", err)
+		}
+		for id, r := range ratings {
+			out[id] = r
+		}
+	}
+	return out, nil
+}
+
+// GetReadChapters returns the IDs of chapters marked as read, per manga ID.
+func (c *Client) GetReadChapters(ctx context.Context, ids []string) (map[string][]string, error) {
+	out := make(map[string][]string, len(ids))
+	for _, batch := range batchIDs(ids, idBatchSize) {
+		params := QueryParams{IDs: batch}.ToValues()
+		params.Set("grouped", "true")
+		var wrapper struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := c.EnsureToken(ctx); err != nil {
+			return nil, err
+		}
+		if err := c.doInto(ctx, http.MethodGet, "/manga/read", params, nil, &wrapper); err != nil {
+			return nil, err
+		}
+		var read map[string][]string
+		if err := decodeMap(wrapper.Data, &read); err != nil {
+			return nil, fmt.Errorf("decode read markers: %w", err)
+		}
+		for id, chapters := range read {
+			out[id] = chapters
+		}
 	}
-	return followedManga, nil
+	return out, nil
+}
+
+// GetUserLists returns one page of the user's custom lists.
+func (c *Client) GetUserLists(ctx context.Context, qp QueryParams) ([]CustomList, Stats, error) {
+	return getPage[CustomList](ctx, c, "/user/list", qp)
+}
+
+// UserLists yields the user's custom lists.
+func (c *Client) UserLists(ctx context.Context) iter.Seq2[CustomList, error] {
+	return Paginate(ctx, QueryParams{}, c.GetUserLists, nil)
+}
+
+// GetAllUserLists returns every custom list of the user.
+func (c *Client) GetAllUserLists(ctx context.Context) ([]CustomList, error) {
+	return Collect(c.UserLists(ctx))
+}
+
+// GetChapters returns the chapters with the given IDs.
+func (c *Client) GetChapters(ctx context.Context, ids []string) ([]Chapter, error) {
+	var out []Chapter
+	for _, batch := range batchIDs(ids, idBatchSize) {
+		params := QueryParams{IDs: batch, Limit: len(batch), ContentRating: allContentRatings}.ToValues()
+		var page []Chapter
+		if err := c.EnsureToken(ctx); err != nil {
+			return nil, err
+		}
+		if err := c.doData(ctx, http.MethodGet, "/chapter", params, nil, &page); err != nil {
+			return nil, err
+		}
+		out = append(out, page...)
+	}
+	return out, nil
+}
 
+// GetMangaByIDs returns the manga with the given IDs, regardless of content rating.
+func (c *Client) GetMangaByIDs(ctx context.Context, ids []string) ([]Manga, error) {
+	var out []Manga
+	for _, batch := range batchIDs(ids, idBatchSize) {
+		page, err := c.GetMangaList(ctx, QueryParams{IDs: batch, Limit: len(batch), ContentRating: allContentRatings})
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, page...)
+	}
+	return out, nil
+}
+
+var allContentRatings = []ContentRating{
+	ContentRatingSafe,
+	ContentRatingSuggestive,
+	ContentRatingErotica,
+	ContentRatingPornographic,
+}
+
+// idBatchSize keeps query strings for ID list endpoints well below URL limits.
+const idBatchSize = 100
+
+func batchIDs(ids []string, size int) [][]string {
+	var batches [][]string
+	for len(ids) > size {
+		batches = append(batches, ids[:size])
+		ids = ids[size:]
+	}
+	if len(ids) > 0 {
+		batches = append(batches, ids)
+	}
+	return batches
+}
+
+// GetAllFollowed returns every manga the user follows.
+func (c *Client) GetAllFollowed(ctx context.Context) ([]Manga, error) {
+	return Collect(c.Follows(ctx))
 }
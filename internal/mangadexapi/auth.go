@@ -3,40 +3,30 @@ package mangadexapi
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
-
-	"gopkg.in/ini.v1"
 )
 
 const authURL = "https://auth.mangadex.org/realms/mangadex/protocol/openid-connect/token"
 
-func (c *Client) LoadAuth(path string) error {
-	var m AuthForm
-	cfg, err := ini.Load(path)
-	if err != nil {
-		return fmt.Errorf("load auth config %s: %w", path, err)
-	}
-	sec := cfg.Section("mangadex")
-	m.Username = sec.Key("username").String()
-	m.Password = sec.Key("password").String()
-	m.ClientID = sec.Key("client_id").String()
-	m.ClientSecret = sec.Key("client_secret").String()
-	c.auth = m
-	return nil
-}
+// defaultTokenLifetime is assumed when the token response has no expires_in.
+const defaultTokenLifetime = 15 * time.Minute
 
-// LoadAuthForm loads authentication directly from an AuthForm struct
+// LoadAuthForm loads authentication directly from an AuthForm struct. The
+// password may be left out if a token is saved for the account.
 func (c *Client) LoadAuthForm(auth AuthForm) error {
 	// Validate required fields
 	if auth.Username == "" {
 		return fmt.Errorf("username is required")
 	}
-	if auth.Password == "" {
+	if auth.Password == "" && !c.HasSavedToken(auth.Username, auth.ClientID) {
 		return fmt.Errorf("password is required")
 	}
 	if auth.ClientID == "" {
@@ -50,7 +40,22 @@ func (c *Client) LoadAuthForm(auth AuthForm) error {
 	return nil
 }
 
+// Username returns the username of the loaded credentials.
+func (c *Client) Username() string {
+	return c.auth.Username
+}
+
+// Authenticate logs in with the password grant.
 func (c *Client) Authenticate(ctx context.Context) error {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.authenticate(ctx)
+}
+
+func (c *Client) authenticate(ctx context.Context) error {
+	if c.auth.Password == "" {
+		return fmt.Errorf("%w: password is required to log in", ErrAuth)
+	}
 	form := url.Values{}
 	form.Set("grant_type", "password")
 	form.Set("username", c.auth.Username)
@@ -58,17 +63,19 @@ func (c *Client) Authenticate(ctx context.Context) error {
 	form.Set("client_id", c.auth.ClientID)
 	form.Set("client_secret", c.auth.ClientSecret)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, authURL, strings.NewReader(form.Encode()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.authURL, strings.NewReader(form.Encode()))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", c.userAgent)
 
-	if err := generalRateLimit.Wait(ctx); err != nil {
-		return fmt.Errorf("rate limit error: %w", err)
+	if err := c.waitAuth(ctx); err != nil {
+		return err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -76,36 +83,49 @@ func (c *Client) Authenticate(ctx context.Context) error {
 
 	if resp.StatusCode != http.StatusOK {
 		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("refresh failed: %s: %s", resp.Status, string(b))
+		return fmt.Errorf("%w: %w", ErrAuth, newError(resp, http.MethodPost, c.authURL, nil, b))
 	}
 
 	var token Token
 	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
 		return err
 	}
+	token.setExpiry(start)
 	c.token = &token
-	c.token.Expiry = time.Now().Add(15 * time.Minute)
+	c.saveToken()
 	return nil
 }
 
+// RefreshToken exchanges the refresh token for a new access token.
 func (c *Client) RefreshToken(ctx context.Context) error {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.refresh(ctx)
+}
+
+func (c *Client) refresh(ctx context.Context) error {
+	if c.token == nil || c.token.RefreshToken == "" {
+		return fmt.Errorf("%w: no refresh token", ErrAuth)
+	}
 	form := url.Values{}
 	form.Set("grant_type", "refresh_token")
 	form.Set("refresh_token", c.token.RefreshToken)
 	form.Set("client_id", c.auth.ClientID)
 	form.Set("client_secret", c.auth.ClientSecret)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, authURL, strings.NewReader(form.Encode()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.authURL, strings.NewReader(form.Encode()))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", c.userAgent)
 
-	if err := generalRateLimit.Wait(ctx); err != nil {
-		return fmt.Errorf("rate limit error: %w", err)
+	if err := c.waitAuth(ctx); err != nil {
+		return err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -113,7 +133,7 @@ func (c *Client) RefreshToken(ctx context.Context) error {
 
 	if resp.StatusCode != http.StatusOK {
 		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("refresh failed: %s: %s", resp.Status, string(b))
+		return fmt.Errorf("%w: refresh: %w", ErrAuth, newError(resp, http.MethodPost, c.authURL, nil, b))
 	}
 
 	var token Token
@@ -126,16 +146,152 @@ func (c *Client) RefreshToken(ctx context.Context) error {
 	if token.RefreshToken != "" {
 		c.token.RefreshToken = token.RefreshToken
 	}
-	c.token.Expiry = time.Now().Add(15 * time.Minute)
+	c.token.ExpiresIn = token.ExpiresIn
+	c.token.setExpiry(start)
+	c.saveToken()
 	return nil
 }
 
+// EnsureToken makes sure the client holds an access token valid for at least
+// another minute. Without one it reuses the token saved for the account (see
+// WithTokenDir) or logs in; an expiring token is refreshed, falling back to a
+// password login when the refresh token is no longer accepted. Concurrent
+// callers wait for a single login or refresh.
 func (c *Client) EnsureToken(ctx context.Context) error {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.token == nil {
+		c.token = c.loadToken()
+	}
+	if c.token == nil {
+		return c.authenticate(ctx)
+	}
 	if time.Until(c.token.Expiry) < time.Minute {
-		err := c.RefreshToken(ctx)
-		if err != nil {
-			return fmt.Errorf("refresh token: %w", err)
-		}
+		return c.renew(ctx)
 	}
 	return nil
 }
+
+// renewToken replaces an access token the API rejected. If another request
+// has already replaced it, the new token is kept.
+func (c *Client) renewToken(ctx context.Context, rejected string) error {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.token != nil && c.token.AccessToken != rejected {
+		return nil
+	}
+	return c.renew(ctx)
+}
+
+// renew refreshes the token, or logs in again if that fails. c.tokenMu must
+// be held.
+func (c *Client) renew(ctx context.Context) error {
+	err := c.refresh(ctx)
+	if err == nil {
+		return nil
+	}
+	if c.auth.Password == "" {
+		return fmt.Errorf("refresh token: %w", err)
+	}
+	if aerr := c.authenticate(ctx); aerr != nil {
+		return fmt.Errorf("refresh token: %w", errors.Join(err, aerr))
+	}
+	return nil
+}
+
+// accessToken returns the current access token, or "" before login.
+func (c *Client) accessToken() string {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.token == nil {
+		return ""
+	}
+	return c.token.AccessToken
+}
+
+// setExpiry sets Expiry from ExpiresIn, counted from when the token was
+// requested.
+func (t *Token) setExpiry(requested time.Time) {
+	d := time.Duration(t.ExpiresIn) * time.Second
+	if d <= 0 {
+		d = defaultTokenLifetime
+	}
+	t.Expiry = requested.Add(d)
+}
+
+// DefaultTokenDir returns the saved token directory under the user config
+// dir.
+func DefaultTokenDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mangadex-import", "tokens"), nil
+}
+
+type savedToken struct {
+	Username string `json:"username"`
+	ClientID string `json:"client_id"`
+	Token
+}
+
+// tokenPath returns the file holding the token of the account, or "" when
+// tokens are not saved.
+func (c *Client) tokenPath(username, clientID string) string {
+	if c.tokenDir == "" || username == "" {
+		return ""
+	}
+	return filepath.Join(c.tokenDir, hashKey(clientID+"\x00"+username)+".json")
+}
+
+// HasSavedToken reports whether a token is saved for the account, so that
+// it can be used without its password.
+func (c *Client) HasSavedToken(username, clientID string) bool {
+	return c.readToken(username, clientID) != nil
+}
+
+// loadToken returns the token saved for the loaded account, if any.
+func (c *Client) loadToken() *Token {
+	return c.readToken(c.auth.Username, c.auth.ClientID)
+}
+
+func (c *Client) readToken(username, clientID string) *Token {
+	path := c.tokenPath(username, clientID)
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var st savedToken
+	if json.Unmarshal(data, &st) != nil || st.Username != username || st.ClientID != clientID || st.RefreshToken == "" {
+		return nil
+	}
+	return &st.Token
+}
+
+// saveToken writes the token to a file only the user can read. Saving is
+// best effort: without it the next run simply logs in again.
+func (c *Client) saveToken() {
+	path := c.tokenPath(c.auth.Username, c.auth.ClientID)
+	if path == "" || c.token == nil {
+		return
+	}
+	data, err := json.Marshal(savedToken{Username: c.auth.Username, ClientID: c.auth.ClientID, Token: *c.token})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil || os.Rename(tmp.Name(), path) != nil {
+		os.Remove(tmp.Name())
+	}
+}
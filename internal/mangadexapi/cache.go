@@ -0,0 +1,237 @@
+package mangadexapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheKind groups cached responses that share a TTL and invalidation rules.
+type CacheKind string
+
+const (
+	CacheManga   CacheKind = "manga"   // GET /manga/{id}
+	CacheSearch  CacheKind = "search"  // GET /manga
+	CacheFollows CacheKind = "follows" // GET /user/follows/manga pages, per account
+)
+
+// CacheKinds lists every cache kind.
+var CacheKinds = []CacheKind{CacheManga, CacheSearch, CacheFollows}
+
+// Default cache TTLs. Follows change on the website too, so they expire
+// sooner than manga metadata.
+var defaultCacheTTLs = map[CacheKind]time.Duration{
+	CacheManga:   24 * time.Hour,
+	CacheSearch:  6 * time.Hour,
+	CacheFollows: time.Hour,
+}
+
+// mangaSubpaths are /manga/{x} endpoints that are not a manga ID.
+var mangaSubpaths = map[string]bool{"status": true, "random": true, "tag": true, "read": true, "draft": true}
+
+// CacheStats counts cache lookups and writes.
+type CacheStats struct {
+	Hits          int64
+	Misses        int64
+	Stores        int64
+	Invalidations int64
+}
+
+func (s CacheStats) String() string {
+	return fmt.Sprintf("%d hits, %d misses, %d stored, %d invalidated", s.Hits, s.Misses, s.Stores, s.Invalidations)
+}
+
+// Cache is an on-disk cache of manga, search and follow list responses. It
+// is safe for concurrent use and may be shared by several clients; see
+// WithCache.
+type Cache struct {
+	dir string
+
+	mu   sync.RWMutex
+	ttls map[CacheKind]time.Duration
+
+	hits, misses, stores, invalidations atomic.Int64
+}
+
+type cacheEntry struct {
+	Key    string          `json:"key"`
+	Stored time.Time       `json:"stored"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// DefaultCacheDir returns the response cache directory under the user cache
+// dir.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mangadex-import", "responses"), nil
+}
+
+// OpenCache opens the cache in dir with the default TTLs.
+func OpenCache(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+	c := &Cache{dir: dir, ttls: make(map[CacheKind]time.Duration)}
+	for k, ttl := range defaultCacheTTLs {
+		c.ttls[k] = ttl
+	}
+	return c, nil
+}
+
+// SetTTL sets how long responses of kind stay fresh. Zero disables caching
+// of that kind.
+func (c *Cache) SetTTL(kind CacheKind, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttls[kind] = ttl
+}
+
+func (c *Cache) ttl(kind CacheKind) time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ttls[kind]
+}
+
+// Stats returns the counters since the cache was opened.
+func (c *Cache) Stats() CacheStats {
+	return CacheStats{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Stores:        c.stores.Load(),
+		Invalidations: c.invalidations.Load(),
+	}
+}
+
+// get returns the cached body for key if it is fresh.
+func (c *Cache) get(kind CacheKind, path, key string) ([]byte, bool) {
+	ttl := c.ttl(kind)
+	if ttl <= 0 {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.misses.Add(1)
+		return nil, false
+	}
+	var e cacheEntry
+	if json.Unmarshal(data, &e) != nil || e.Key != key || time.Since(e.Stored) > ttl {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	return e.Body, true
+}
+
+// put stores body under key, replacing the file atomically.
+func (c *Cache) put(kind CacheKind, path, key string, body []byte) {
+	if c.ttl(kind) <= 0 || !json.Valid(body) {
+		return
+	}
+	data, err := json.Marshal(cacheEntry{Key: key, Stored: time.Now(), Body: body})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil || os.Rename(tmp.Name(), path) != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	c.stores.Add(1)
+}
+
+// lookup resolves a GET request to baseURL to its cache kind, file and key.
+// Requests that are not cached return ok false.
+func (c *Cache) lookup(baseURL, account, endpoint string, params url.Values) (kind CacheKind, path, key string, ok bool) {
+	// url.Values.Encode sorts by key, normalizing the query. The base URL
+	// keeps responses of a sandbox or mock server apart from the real API's.
+	key = baseURL + endpoint + "?" + params.Encode()
+	switch {
+	case endpoint == "/manga":
+		return CacheSearch, filepath.Join(c.dir, string(CacheSearch), hashKey(key)+".json"), key, true
+	case endpoint == "/user/follows/manga":
+		key = account + " " + key
+		return CacheFollows, filepath.Join(c.followsDir(account), hashKey(key)+".json"), key, true
+	case strings.HasPrefix(endpoint, "/manga/"):
+		id := strings.TrimPrefix(endpoint, "/manga/")
+		if id == "" || strings.Contains(id, "/") || mangaSubpaths[id] {
+			return "", "", "", false
+		}
+		return CacheManga, filepath.Join(c.mangaDir(id), hashKey(key)+".json"), key, true
+	}
+	return "", "", "", false
+}
+
+func (c *Cache) mangaDir(id string) string {
+	return filepath.Join(c.dir, string(CacheManga), hashKey(id))
+}
+
+func (c *Cache) followsDir(account string) string {
+	return filepath.Join(c.dir, string(CacheFollows), hashKey(account))
+}
+
+// InvalidateManga drops the cached metadata of a manga and the follow list
+// pages of account, which may list it.
+func (c *Cache) InvalidateManga(account, id string) error {
+	return errors.Join(c.removeAll(c.mangaDir(id)), c.InvalidateFollows(account))
+}
+
+// InvalidateFollows drops the cached follow list pages of account.
+func (c *Cache) InvalidateFollows(account string) error {
+	return c.removeAll(c.followsDir(account))
+}
+
+// Invalidate drops every cached response of the given kinds, or of all kinds
+// if none are given.
+func (c *Cache) Invalidate(kinds ...CacheKind) error {
+	if len(kinds) == 0 {
+		kinds = CacheKinds
+	}
+	var errs []error
+	for _, k := range kinds {
+		errs = append(errs, c.removeAll(filepath.Join(c.dir, string(k))))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Cache) removeAll(dir string) error {
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("invalidate cache: %w", err)
+	}
+	c.invalidations.Add(1)
+	return nil
+}
+
+func hashKey(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:32]
+}
+
+// WithCache serves manga, search and follow list responses from cache while
+// they are fresh, and drops the affected entries after follow, status and
+// rating writes.
+func WithCache(cache *Cache) Option {
+	return func(c *Client) { c.cache = cache }
+}
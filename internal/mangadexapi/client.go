@@ -4,16 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"reflect"
 	"strings"
-	"time"
-
-	"golang.org/x/time/rate"
 )
 
 const (
@@ -21,24 +17,53 @@ const (
 	userAgent = "MangaDex-Import/0.1 (https://github.com/Another0Noob/mangadex-import)"
 )
 
-var generalRateLimit = rate.NewLimiter(rate.Every(time.Second/time.Duration(5)), 5)
-
 // NewClient creates a new MangaDex API client.
-func NewClient() *Client {
-	return &Client{
+func NewClient(opts ...Option) *Client {
+	c := &Client{
 		httpClient: &http.Client{},
 		baseURL:    baseURL,
+		authURL:    authURL,
 		userAgent:  userAgent,
+		limits:     defaultLimiters(),
+		retry:      DefaultRetryPolicy,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-// doRequest performs an HTTP request to the MangaDex API (raw, no JSON decoding).
-func (c *Client) doRequest(ctx context.Context, method, endpoint string, params url.Values, body any) (*http.Response, error) {
-	// Rate limiting
-	if err := generalRateLimit.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limit error: %w", err)
+// SetWriteHook installs a hook around follow, status and rating writes, e.g.
+// to journal them. A nil hook removes it.
+func (c *Client) SetWriteHook(h WriteHook) {
+	c.writeHook = h
+}
+
+// doWrite runs write through the write hook, if any, and drops the cache
+// entries the write made stale.
+func (c *Client) doWrite(ctx context.Context, op WriteOp, write func() error) error {
+	if c.cache != nil {
+		w := write
+		write = func() error {
+			if err := w(); err != nil {
+				return err
+			}
+			// The write succeeded; a failed invalidation only means a
+			// stale cache file, which expires with its TTL.
+			_ = c.cache.InvalidateManga(c.auth.Username, op.MangaID)
+			return nil
+		}
 	}
+	if c.writeHook == nil {
+		return write()
+	}
+	return c.writeHook(ctx, op, write)
+}
 
+// doRequest performs an HTTP request to the MangaDex API (raw, no JSON decoding).
+// Rate limited, server and network errors are retried per c.retry. A 401 for
+// the current access token renews it and retries once.
+func (c *Client) doRequest(ctx context.Context, method, endpoint string, params url.Values, body any) (*http.Response, error) {
 	// Build URL
 	fullURL := c.baseURL + endpoint
 	if len(params) > 0 {
@@ -46,42 +71,108 @@ func (c *Client) doRequest(ctx context.Context, method, endpoint string, params
 	}
 
 	// Prepare body
-	var bodyReader io.Reader
+	var bodyBytes []byte
 	if body != nil {
-		bodyBytes, err := json.Marshal(body)
+		var err error
+		bodyBytes, err = json.Marshal(body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
-		bodyReader = bytes.NewReader(bodyBytes)
 	}
 
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, method, fullURL, bodyReader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("User-Agent", c.userAgent)
-	if c.token != nil {
-		req.Header.Set("Authorization", "Bearer "+c.token.AccessToken)
-	}
-	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
-	}
+	renewed := false
+	for attempt := 1; ; attempt++ {
+		// Rate limiting
+		if err := c.waitAPI(ctx, method); err != nil {
+			return nil, err
+		}
 
-	// Send request
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
+		// Create request
+		var bodyReader io.Reader
+		if bodyBytes != nil {
+			bodyReader = bytes.NewReader(bodyBytes)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, fullURL, bodyReader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("User-Agent", c.userAgent)
+		token := c.accessToken()
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
 
-	return resp, nil
-}
+		// Send request
+		resp, err := c.httpClient.Do(req)
+		if err != nil && ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+		if err == nil && resp.StatusCode == http.StatusUnauthorized && token != "" && !renewed {
+			renewed = true
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if err := c.renewToken(ctx, token); err != nil {
+				return nil, fmt.Errorf("renew token: %w", err)
+			}
+			attempt--
+			continue
+		}
+		if err == nil && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return resp, nil
+		}
 
-var ErrNotFound = errors.New("mangadex: not found")
+		delay, retry := c.retry.retryDelay(method, endpoint, attempt, resp)
+		if !retry {
+			if err != nil {
+				return nil, fmt.Errorf("failed to send request: %w", err)
+			}
+			return resp, nil
+		}
+
+		ev := RetryEvent{Method: method, Endpoint: endpoint, Attempt: attempt, Err: err, Delay: delay}
+		if resp != nil {
+			ev.Status = resp.StatusCode
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if c.onRetry != nil {
+			c.onRetry(ev)
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
 
 // doEnvelope runs the request, reads body, parses (or synthesizes) an Envelope,
-// normalizing 404 handling and returning ErrNotFound where appropriate.
+// and returns an *Error for error responses (matching ErrNotFound for 404s).
+// Cacheable GET requests are served from and stored in c.cache.
 func (c *Client) doEnvelope(ctx context.Context, method, endpoint string, params url.Values, body any) (*Envelope, []byte, error) {
+	if c.cache == nil || method != http.MethodGet {
+		return c.fetchEnvelope(ctx, method, endpoint, params, body)
+	}
+	kind, path, key, ok := c.cache.lookup(c.baseURL, c.auth.Username, endpoint, params)
+	if !ok {
+		return c.fetchEnvelope(ctx, method, endpoint, params, body)
+	}
+	if b, hit := c.cache.get(kind, path, key); hit {
+		var env Envelope
+		if json.Unmarshal(b, &env) == nil {
+			return &env, b, nil
+		}
+	}
+	env, b, err := c.fetchEnvelope(ctx, method, endpoint, params, body)
+	if err == nil {
+		c.cache.put(kind, path, key, b)
+	}
+	return env, b, err
+}
+
+// fetchEnvelope is doEnvelope without the cache.
+func (c *Client) fetchEnvelope(ctx context.Context, method, endpoint string, params url.Values, body any) (*Envelope, []byte, error) {
 	resp, err := c.doRequest(ctx, method, endpoint, params, body)
 	if err != nil {
 		return nil, nil, err
@@ -93,44 +184,22 @@ func (c *Client) doEnvelope(ctx context.Context, method, endpoint string, params
 		return nil, nil, fmt.Errorf("read body: %w", err)
 	}
 
-	// Fast 404 (no JSON needed)
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, b, ErrNotFound
-	}
+	ok := resp.StatusCode >= 200 && resp.StatusCode < 300
 
 	var env Envelope
 	// Try to decode JSON regardless of status to inspect API errors
 	if json.Unmarshal(b, &env) != nil {
 		// If not JSON and not success -> raw error
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			return nil, b, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(b))
+		if !ok {
+			return nil, b, newError(resp, method, endpoint, nil, b)
 		}
 		// If success but envelope missing -> treat as protocol error
 		return nil, b, fmt.Errorf("decode envelope: not valid JSON (status %d): %s", resp.StatusCode, string(b))
 	}
 
-	// Non-2xx: inspect API errors
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		if len(env.Errors) > 0 {
-			first := env.Errors[0]
-			if first.Status == http.StatusNotFound {
-				return &env, b, ErrNotFound
-			}
-			return &env, b, fmt.Errorf("api error (%d): %s: %s", first.Status, first.Title, first.Detail)
-		}
-		return &env, b, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(b))
-	}
-
-	// Result-level error (even with HTTP 2xx)
-	if env.Result == "error" {
-		if len(env.Errors) > 0 {
-			first := env.Errors[0]
-			if first.Status == http.StatusNotFound {
-				return &env, b, ErrNotFound
-			}
-			return &env, b, fmt.Errorf("api error: %s (%d): %s", first.Title, first.Status, first.Detail)
-		}
-		return &env, b, fmt.Errorf("api error: result=error with no details")
+	// Non-2xx, or result-level error even with HTTP 2xx
+	if !ok || env.Result == "error" {
+		return &env, b, newError(resp, method, endpoint, &env, b)
 	}
 
 	return &env, b, nil
@@ -195,6 +264,16 @@ func decodeData(raw json.RawMessage, out any) error {
 	return fmt.Errorf("unhandled data shape: %s", string(raw))
 }
 
+// decodeMap decodes a JSON object into out. The API encodes empty maps as
+// empty arrays, so "[]" (and a missing value) leave out untouched.
+func decodeMap(raw json.RawMessage, out any) error {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("[]")) || bytes.Equal(trimmed, []byte("null")) {
+		return nil
+	}
+	return json.Unmarshal(trimmed, out)
+}
+
 // ToValues converts QueryParams to url.Values for the request.
 func (q QueryParams) ToValues() url.Values {
 	v := url.Values{}
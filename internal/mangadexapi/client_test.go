@@ -0,0 +1,419 @@
+package mangadexapi_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi/mdfake"
+)
+
+func newTestClient(t *testing.T) (*mangadexapi.Client, *mdfake.Server) {
+	t.Helper()
+	srv := mdfake.New()
+	t.Cleanup(srv.Close)
+
+	client := mangadexapi.NewClient(srv.Options()...)
+	if err := client.LoadAuthForm(srv.AuthForm()); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Authenticate(context.Background()); err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	return client, srv
+}
+
+func TestOptionsLeaveHTTPClientUntouched(t *testing.T) {
+	srv := mdfake.New()
+	t.Cleanup(srv.Close)
+	hc := srv.Client()
+	transport := hc.Transport
+
+	client := mangadexapi.NewClient(append(srv.Options(),
+		mangadexapi.WithTimeout(time.Second),
+		mangadexapi.WithRecorder(mangadexapi.NewRecorder(nil)),
+	)...)
+	if err := client.LoadAuthForm(srv.AuthForm()); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Authenticate(context.Background()); err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if hc.Timeout != 0 || hc.Transport != transport {
+		t.Errorf("options changed the server's HTTP client: timeout %s, transport %T", hc.Timeout, hc.Transport)
+	}
+}
+
+func TestGetAllFollowedPaginates(t *testing.T) {
+	client, srv := newTestClient(t)
+	for i := range 250 {
+		id := fmt.Sprintf("m%03d", i)
+		srv.AddManga(mdfake.NewManga(id, "Title "+id))
+		srv.Follow(id)
+	}
+
+	got, err := client.GetAllFollowed(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 250 {
+		t.Fatalf("got %d follows, want 250", len(got))
+	}
+	if got[0].ID != "m000" || got[249].ID != "m249" {
+		t.Errorf("unexpected order: first %s, last %s", got[0].ID, got[249].ID)
+	}
+	if n := srv.Count(http.MethodGet, "/user/follows/manga"); n != 3 {
+		t.Errorf("made %d page requests, want 3", n)
+	}
+}
+
+func TestRetriesRateLimitedRequests(t *testing.T) {
+	client, srv := newTestClient(t)
+	srv.AddManga(mdfake.NewManga("a", "Alpha"))
+	srv.RateLimit(2, 0)
+
+	var retries []mangadexapi.RetryEvent
+	client.SetRetryObserver(func(ev mangadexapi.RetryEvent) { retries = append(retries, ev) })
+
+	if err := client.FollowManga(context.Background(), "a"); err != nil {
+		t.Fatalf("follow: %v", err)
+	}
+	if len(retries) != 2 || retries[0].Status != http.StatusTooManyRequests {
+		t.Errorf("retry events = %+v, want two 429s", retries)
+	}
+	if got := srv.Followed(); len(got) != 1 || got[0] != "a" {
+		t.Errorf("followed = %v", got)
+	}
+}
+
+func TestDoesNotRetryUnsafeWrites(t *testing.T) {
+	client, srv := newTestClient(t)
+	srv.Fail(http.MethodPost, "/list", http.StatusServiceUnavailable, 5)
+
+	_, err := client.CreateList(context.Background(), "list", "private", nil)
+	if !mangadexapi.IsServerError(err) {
+		t.Fatalf("err = %v, want server error", err)
+	}
+	if n := srv.Count(http.MethodPost, "/list"); n != 1 {
+		t.Errorf("sent %d create requests, want 1", n)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	client, srv := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.GetManga(ctx, "missing", mangadexapi.QueryParams{})
+	if !errors.Is(err, mangadexapi.ErrNotFound) {
+		t.Errorf("missing manga: err = %v, want ErrNotFound", err)
+	}
+
+	srv.Fail(http.MethodGet, "/manga", http.StatusBadRequest, 1)
+	_, err = client.GetMangaList(ctx, mangadexapi.QueryParams{Title: "x"})
+	var apiErr *mangadexapi.Error
+	if !mangadexapi.IsBadRequest(err) || !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *Error with status 400", err)
+	}
+	if apiErr.Endpoint != "/manga" || apiErr.RequestID == "" || len(apiErr.Errors) != 1 {
+		t.Errorf("error details = %+v", apiErr)
+	}
+
+	srv.Fail(http.MethodGet, "/manga", http.StatusUnauthorized, 2)
+	_, err = client.GetMangaList(ctx, mangadexapi.QueryParams{Title: "x"})
+	if !mangadexapi.IsUnauthorized(err) {
+		t.Errorf("rejected token: err = %v, want unauthorized", err)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	srv := mdfake.New()
+	srv.AddManga(mdfake.NewManga("a", "Alpha"), mdfake.NewManga("b", "Alphabet"))
+	ctx := context.Background()
+
+	rec := mangadexapi.NewRecorder(nil)
+	client := mangadexapi.NewClient(append(srv.Options(), mangadexapi.WithRecorder(rec))...)
+	if err := client.LoadAuthForm(srv.AuthForm()); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Authenticate(ctx); err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	want, err := client.GetMangaList(ctx, mangadexapi.QueryParams{Title: "alpha"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	path := filepath.Join(t.TempDir(), "recording.json")
+	if err := rec.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"password=" + mdfake.Password, "client_secret=" + mdfake.ClientSecret, "access-", "refresh-"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("recording contains %q", secret)
+		}
+	}
+
+	replayer, err := mangadexapi.LoadReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := mangadexapi.NewClient(
+		mangadexapi.WithTransport(replayer),
+		mangadexapi.WithBaseURL(srv.URL),
+		mangadexapi.WithAuthURL(srv.AuthURL()),
+		mangadexapi.WithRetryPolicy(mangadexapi.RetryPolicy{MaxAttempts: 1}),
+	)
+	if err := replay.LoadAuthForm(mangadexapi.AuthForm{Username: "someone", Password: "else", ClientID: "c", ClientSecret: "s"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := replay.Authenticate(ctx); err != nil {
+		t.Fatalf("replayed authenticate: %v", err)
+	}
+	got, err := replay.GetMangaList(ctx, mangadexapi.QueryParams{Title: "alpha"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) || got[0].ID != want[0].ID {
+		t.Errorf("replayed %d results, want %d", len(got), len(want))
+	}
+
+	if _, err := replay.GetMangaList(ctx, mangadexapi.QueryParams{Title: "other"}); err == nil {
+		t.Error("unrecorded request succeeded")
+	}
+}
+
+func TestCache(t *testing.T) {
+	srv := mdfake.New()
+	t.Cleanup(srv.Close)
+	srv.AddManga(mdfake.NewManga("a", "Alpha"), mdfake.NewManga("b", "Beta"))
+	srv.Follow("a")
+	ctx := context.Background()
+
+	cache, err := mangadexapi.OpenCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := mangadexapi.NewClient(append(srv.Options(), mangadexapi.WithCache(cache))...)
+	if err := client.LoadAuthForm(srv.AuthForm()); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Authenticate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if _, err := client.GetMangaList(ctx, mangadexapi.QueryParams{Title: "alpha"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.GetAllFollowed(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := srv.Count(http.MethodGet, "/manga"); n != 1 {
+		t.Errorf("sent %d searches, want 1", n)
+	}
+	if n := srv.Count(http.MethodGet, "/user/follows/manga"); n != 1 {
+		t.Errorf("fetched follows %d times, want 1", n)
+	}
+
+	if err := client.FollowManga(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	followed, err := client.GetAllFollowed(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(followed) != 2 {
+		t.Errorf("got %d follows after following, want 2", len(followed))
+	}
+	if st := cache.Stats(); st.Hits != 2 || st.Invalidations == 0 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestCacheSeparatesBaseURLs(t *testing.T) {
+	cache, err := mangadexapi.OpenCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for i := range 2 {
+		srv := mdfake.New()
+		t.Cleanup(srv.Close)
+		srv.AddManga(mdfake.NewManga("a", "Alpha"))
+		client := mangadexapi.NewClient(append(srv.Options(), mangadexapi.WithCache(cache))...)
+		if err := client.LoadAuthForm(srv.AuthForm()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.GetMangaList(ctx, mangadexapi.QueryParams{Title: "alpha"}); err != nil {
+			t.Fatal(err)
+		}
+		if n := srv.Count(http.MethodGet, "/manga"); n != 1 {
+			t.Errorf("server %d: sent %d searches, want 1", i, n)
+		}
+	}
+}
+
+func TestTokenReuse(t *testing.T) {
+	srv := mdfake.New()
+	t.Cleanup(srv.Close)
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	newClient := func(form mangadexapi.AuthForm) *mangadexapi.Client {
+		c := mangadexapi.NewClient(append(srv.Options(), mangadexapi.WithTokenDir(dir))...)
+		if err := c.LoadAuthForm(form); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.EnsureToken(ctx); err != nil {
+			t.Fatalf("ensure token: %v", err)
+		}
+		return c
+	}
+
+	form := srv.AuthForm()
+	noPassword := form
+	noPassword.Password = ""
+	if err := mangadexapi.NewClient(mangadexapi.WithTokenDir(dir)).LoadAuthForm(noPassword); err == nil {
+		t.Error("loaded credentials without password or saved token")
+	}
+
+	newClient(form)
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(paths) != 1 {
+		t.Fatalf("saved %d token files, want 1", len(paths))
+	}
+	if info, err := os.Stat(paths[0]); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("token file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	// The saved token stands in for the password.
+	client := newClient(noPassword)
+	if n := srv.Count(http.MethodPost, "/auth/token"); n != 1 {
+		t.Errorf("sent %d token requests, want 1", n)
+	}
+	if _, err := client.GetMangaList(ctx, mangadexapi.QueryParams{Title: "x"}); err != nil {
+		t.Errorf("request with saved token: %v", err)
+	}
+}
+
+func TestTokenRenewal(t *testing.T) {
+	srv := mdfake.New()
+	t.Cleanup(srv.Close)
+	srv.AddManga(mdfake.NewManga("a", "Alpha"))
+	ctx := context.Background()
+
+	client := mangadexapi.NewClient(srv.Options()...)
+	if err := client.LoadAuthForm(srv.AuthForm()); err != nil {
+		t.Fatal(err)
+	}
+	// No Authenticate: the first request logs in.
+	if _, err := client.GetMangaList(ctx, mangadexapi.QueryParams{Title: "alpha"}); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+
+	srv.RevokeTokens()
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 8 {
+		wg.Go(func() {
+			_, err := client.GetMangaList(ctx, mangadexapi.QueryParams{Title: "alpha"})
+			errs <- err
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("request after revocation: %v", err)
+		}
+	}
+	if n := srv.Count(http.MethodPost, "/auth/token"); n != 2 {
+		t.Errorf("sent %d token requests, want a login and one refresh", n)
+	}
+}
+
+func TestPaginateStopsOnEmptyPage(t *testing.T) {
+	calls := 0
+	fetch := func(ctx context.Context, qp mangadexapi.QueryParams) ([]int, mangadexapi.Stats, error) {
+		calls++
+		if qp.Offset > 0 {
+			return nil, mangadexapi.Stats{Total: 5}, nil
+		}
+		return []int{1, 2}, mangadexapi.Stats{Total: 5}, nil
+	}
+	got, err := mangadexapi.Collect(mangadexapi.Paginate(context.Background(), mangadexapi.QueryParams{}, fetch, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || calls != 2 {
+		t.Errorf("got %v in %d calls, want 2 items in 2 calls", got, calls)
+	}
+}
+
+func TestSearchPastOffsetCap(t *testing.T) {
+	client, srv := newTestClient(t)
+	const n = 10150
+	ms := make([]mangadexapi.Manga, n)
+	for i := range ms {
+		ms[i] = mdfake.NewManga(fmt.Sprintf("m%05d", i), "Title")
+	}
+	srv.AddManga(ms...)
+
+	got, err := client.GetMangaList(context.Background(), mangadexapi.QueryParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAllOnce(t, got, n)
+}
+
+func TestGetAllFollowedPastOffsetCap(t *testing.T) {
+	client, srv := newTestClient(t)
+	const n = 10050
+	ms := make([]mangadexapi.Manga, n)
+	ids := make([]string, n)
+	for i := range ms {
+		ids[i] = fmt.Sprintf("m%05d", i)
+		ms[i] = mdfake.NewManga(ids[i], "Title")
+	}
+	srv.AddManga(ms...)
+	// Followed newest first, so the creation-date windows list them in a
+	// different order than the first pass.
+	slices.Reverse(ids)
+	srv.Follow(ids...)
+
+	got, err := client.GetAllFollowed(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAllOnce(t, got, n)
+}
+
+// checkAllOnce fails unless got holds n distinct manga.
+func checkAllOnce(t *testing.T, got []mangadexapi.Manga, n int) {
+	t.Helper()
+	seen := make(map[string]bool)
+	for _, m := range got {
+		if seen[m.ID] {
+			t.Fatalf("%s returned twice", m.ID)
+		}
+		seen[m.ID] = true
+	}
+	if len(seen) != n {
+		t.Fatalf("got %d manga, want %d", len(seen), n)
+	}
+}
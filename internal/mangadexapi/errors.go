@@ -0,0 +1,111 @@
+package mangadexapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("mangadex: not found")
+
+// ErrAuth is returned when the token endpoint rejects a login or refresh, or
+// no credentials are left to log in with. The status helpers such as
+// IsBadRequest report API requests only and are false for it.
+var ErrAuth = errors.New("mangadex: authentication failed")
+
+// Error is an error response from the MangaDex API: a non-2xx status or a
+// 2xx envelope with result "error". errors.Is(err, ErrNotFound) reports 404s.
+type Error struct {
+	Method     string
+	Endpoint   string
+	StatusCode int
+	Errors     []APIError    // every error entry of the envelope, if any
+	RequestID  string        // X-Request-ID, for reports to MangaDex
+	RetryAfter time.Duration // from X-RateLimit-Retry-After or Retry-After
+	Body       string        // raw body when it was not a JSON envelope
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "mangadex: %s %s: status %d", e.Method, e.Endpoint, e.StatusCode)
+	for _, ae := range e.Errors {
+		fmt.Fprintf(&b, ": %s", ae.Title)
+		if ae.Detail != "" {
+			fmt.Fprintf(&b, " (%s)", ae.Detail)
+		}
+	}
+	if len(e.Errors) == 0 && e.Body != "" {
+		fmt.Fprintf(&b, ": %s", e.Body)
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " [request %s]", e.RequestID)
+	}
+	return b.String()
+}
+
+// Is makes errors.Is(err, ErrNotFound) true for 404 responses.
+func (e *Error) Is(target error) bool {
+	return target == ErrNotFound && e.status() == http.StatusNotFound
+}
+
+// status returns the HTTP status, falling back to the first API error's
+// status for result "error" envelopes sent with 2xx.
+func (e *Error) status() int {
+	if e.StatusCode >= 200 && e.StatusCode < 300 && len(e.Errors) > 0 {
+		return e.Errors[0].Status
+	}
+	return e.StatusCode
+}
+
+func newError(resp *http.Response, method, endpoint string, env *Envelope, body []byte) *Error {
+	e := &Error{
+		Method:     method,
+		Endpoint:   endpoint,
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+	if env != nil {
+		e.Errors = env.Errors
+	} else {
+		e.Body = string(body)
+	}
+	if d, ok := retryAfter(resp.Header); ok {
+		e.RetryAfter = d
+	}
+	return e
+}
+
+func hasStatus(err error, match func(int) bool) bool {
+	if errors.Is(err, ErrAuth) {
+		return false
+	}
+	var e *Error
+	return errors.As(err, &e) && match(e.status())
+}
+
+// IsRateLimited reports a 429 response; see Error.RetryAfter.
+func IsRateLimited(err error) bool {
+	return hasStatus(err, func(s int) bool { return s == http.StatusTooManyRequests })
+}
+
+// IsUnauthorized reports a 401 response, usually an expired or revoked token.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, func(s int) bool { return s == http.StatusUnauthorized })
+}
+
+// IsForbidden reports a 403 response.
+func IsForbidden(err error) bool {
+	return hasStatus(err, func(s int) bool { return s == http.StatusForbidden })
+}
+
+// IsBadRequest reports a 400 response, a request the API rejected as invalid.
+func IsBadRequest(err error) bool {
+	return hasStatus(err, func(s int) bool { return s == http.StatusBadRequest })
+}
+
+// IsServerError reports a 5xx response.
+func IsServerError(err error) bool {
+	return hasStatus(err, func(s int) bool { return s >= 500 })
+}
@@ -0,0 +1,601 @@
+// Package mdfake is an in-memory fake of the MangaDex API for tests. It
+// serves the subset of endpoints mangadexapi.Client uses from seeded
+// fixtures, paginates like MangaDex, and can inject rate limiting and errors.
+package mdfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+	"golang.org/x/time/rate"
+)
+
+// Credentials accepted by the token endpoint.
+const (
+	Username     = "user"
+	Password     = "pass"
+	ClientID     = "client"
+	ClientSecret = "secret"
+)
+
+// maxWindow mirrors MangaDex refusing offset+limit past 10,000.
+const maxWindow = 10000
+
+// Server is a running fake. Its state is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	order    []string // manga IDs in insertion order
+	manga    map[string]mangadexapi.Manga
+	follows  []string
+	statuses map[string]mangadexapi.ReadingStatus
+	ratings  map[string]int
+	tokens   map[string]bool
+	failures []failure
+	limited  int
+	retry    time.Duration
+	requests []string
+	tokenN   int
+}
+
+type failure struct {
+	method string
+	path   string // prefix
+	status int
+	times  int
+}
+
+// New starts a fake server with an empty catalogue. Close it when done.
+func New() *Server {
+	s := &Server{
+		manga:    make(map[string]mangadexapi.Manga),
+		statuses: make(map[string]mangadexapi.ReadingStatus),
+		ratings:  make(map[string]int),
+		tokens:   make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /auth/token", s.handleToken)
+	mux.HandleFunc("GET /manga", s.auth(s.handleMangaList))
+	mux.HandleFunc("GET /manga/{id}", s.auth(s.handleManga))
+	mux.HandleFunc("GET /manga/status", s.auth(s.handleStatuses))
+	mux.HandleFunc("GET /manga/{id}/status", s.auth(s.handleStatus))
+	mux.HandleFunc("POST /manga/{id}/status", s.auth(s.handleSetStatus))
+	mux.HandleFunc("POST /manga/{id}/follow", s.auth(s.handleFollow))
+	mux.HandleFunc("DELETE /manga/{id}/follow", s.auth(s.handleUnfollow))
+	mux.HandleFunc("GET /user/follows/manga", s.auth(s.handleFollows))
+	mux.HandleFunc("GET /user/follows/manga/{id}", s.auth(s.handleIsFollowed))
+	mux.HandleFunc("GET /rating", s.auth(s.handleRatings))
+	mux.HandleFunc("POST /rating/{id}", s.auth(s.handleSetRating))
+	mux.HandleFunc("DELETE /rating/{id}", s.auth(s.handleDeleteRating))
+
+	s.Server = httptest.NewServer(s.inject(mux))
+	return s
+}
+
+// AuthURL is the token endpoint for mangadexapi.WithAuthURL.
+func (s *Server) AuthURL() string {
+	return s.URL + "/auth/token"
+}
+
+// AuthForm returns credentials the fake accepts.
+func (s *Server) AuthForm() mangadexapi.AuthForm {
+	return mangadexapi.AuthForm{Username: Username, Password: Password, ClientID: ClientID, ClientSecret: ClientSecret}
+}
+
+// Options points a client at the fake, without rate limits and with short
+// retry delays so tests run fast.
+func (s *Server) Options() []mangadexapi.Option {
+	return []mangadexapi.Option{
+		mangadexapi.WithHTTPClient(s.Client()),
+		mangadexapi.WithBaseURL(s.URL),
+		mangadexapi.WithAuthURL(s.AuthURL()),
+		mangadexapi.WithRateLimiter(rate.NewLimiter(rate.Inf, 0)),
+		mangadexapi.WithWriteRateLimiter(rate.NewLimiter(rate.Inf, 0)),
+		mangadexapi.WithAuthRateLimiter(rate.NewLimiter(rate.Inf, 0)),
+		mangadexapi.WithGlobalRateLimiter(nil),
+		mangadexapi.WithRetryPolicy(mangadexapi.RetryPolicy{
+			MaxAttempts: 3,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    10 * time.Millisecond,
+		}),
+	}
+}
+
+// NewManga builds a manga fixture with an English title and alt titles.
+func NewManga(id, title string, altTitles ...string) mangadexapi.Manga {
+	m := mangadexapi.Manga{
+		ID:   id,
+		Type: "manga",
+		Attributes: mangadexapi.MangaAttributes{
+			Title:            map[string]string{"en": title},
+			Links:            map[string]string{},
+			OriginalLanguage: "ja",
+		},
+	}
+	for _, alt := range altTitles {
+		m.Attributes.AltTitles = append(m.Attributes.AltTitles, map[string]string{"en": alt})
+	}
+	return m
+}
+
+// createdBase is the creation time of the first manga added without one;
+// later ones are a second apart.
+var createdBase = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// AddManga seeds manga into the catalogue. Manga without a creation time get
+// one after all earlier manga.
+func (s *Server) AddManga(ms ...mangadexapi.Manga) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range ms {
+		if _, ok := s.manga[m.ID]; !ok {
+			s.order = append(s.order, m.ID)
+		}
+		if m.Attributes.CreatedAt == "" {
+			m.Attributes.CreatedAt = createdBase.Add(time.Duration(len(s.order)) * time.Second).Format(time.RFC3339)
+		}
+		s.manga[m.ID] = m
+	}
+}
+
+// Follow seeds follows of catalogue manga.
+func (s *Server) Follow(ids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		s.follow(id)
+	}
+}
+
+func (s *Server) follow(id string) {
+	for _, f := range s.follows {
+		if f == id {
+			return
+		}
+	}
+	s.follows = append(s.follows, id)
+}
+
+// Followed returns the followed IDs in follow order.
+func (s *Server) Followed() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.follows...)
+}
+
+// SetStatus seeds a reading status.
+func (s *Server) SetStatus(id string, status mangadexapi.ReadingStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[id] = status
+}
+
+// Status returns the reading status of a manga.
+func (s *Server) Status(id string) mangadexapi.ReadingStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.statuses[id]
+}
+
+// Fail makes the next times requests whose method matches and whose path
+// starts with path fail with status.
+func (s *Server) Fail(method, path string, status, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{method: method, path: path, status: status, times: times})
+}
+
+// ClearFailures removes failures added with Fail.
+func (s *Server) ClearFailures() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = nil
+}
+
+// RateLimit answers the next n API requests with 429 and an
+// X-RateLimit-Retry-After of retryAfter from now.
+func (s *Server) RateLimit(n int, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limited, s.retry = n, retryAfter
+}
+
+// Requests returns "METHOD /path" for every request received.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// Count returns how many requests matched method and path prefix.
+func (s *Server) Count(method, path string) int {
+	n := 0
+	for _, r := range s.Requests() {
+		if strings.HasPrefix(r, method+" "+path) {
+			n++
+		}
+	}
+	return n
+}
+
+// inject logs requests and applies rate limiting and injected failures.
+func (s *Server) inject(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+		if s.limited > 0 && r.URL.Path != "/auth/token" {
+			s.limited--
+			retry := s.retry
+			s.mu.Unlock()
+			w.Header().Set("X-RateLimit-Retry-After", strconv.FormatInt(time.Now().Add(retry).Unix(), 10))
+			writeError(w, http.StatusTooManyRequests, "rate_limit_exceeded", "too many requests")
+			return
+		}
+		for i := range s.failures {
+			f := &s.failures[i]
+			if f.times > 0 && f.method == r.Method && strings.HasPrefix(r.URL.Path, f.path) {
+				f.times--
+				s.mu.Unlock()
+				writeError(w, f.status, "injected", "injected failure")
+				return
+			}
+		}
+		s.mu.Unlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// auth rejects requests without a token issued by the fake.
+func (s *Server) auth(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		s.mu.Lock()
+		ok := s.tokens[token]
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "unauthorized_http_exception", "not logged in")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// RevokeTokens invalidates all issued access tokens, as if they expired.
+func (s *Server) RevokeTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]bool)
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.PostForm.Get("client_id") != ClientID || r.PostForm.Get("client_secret") != ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	switch r.PostForm.Get("grant_type") {
+	case "password":
+		if r.PostForm.Get("username") != Username || r.PostForm.Get("password") != Password {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_grant"})
+			return
+		}
+	case "refresh_token":
+		if !strings.HasPrefix(r.PostForm.Get("refresh_token"), "refresh-") {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	s.mu.Lock()
+	s.tokenN++
+	access := fmt.Sprintf("access-%d", s.tokenN)
+	s.tokens[access] = true
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token":  access,
+		"refresh_token": fmt.Sprintf("refresh-%d", s.tokenN),
+		"expires_in":    900,
+		"token_type":    "Bearer",
+	})
+}
+
+func (s *Server) handleMangaList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	title := fold(q.Get("title"))
+	ids := make(map[string]bool)
+	for _, id := range q["ids[]"] {
+		ids[id] = true
+	}
+
+	s.mu.Lock()
+	var list []mangadexapi.Manga
+	for _, id := range s.order {
+		m := s.manga[id]
+		if len(ids) > 0 && !ids[id] {
+			continue
+		}
+		if title != "" && !titleMatches(m, title) {
+			continue
+		}
+		list = append(list, m)
+	}
+	s.mu.Unlock()
+
+	writeCollection(w, r, list)
+}
+
+func (s *Server) handleManga(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	m, ok := s.manga[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found_http_exception", "manga not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"result": "ok", "response": "entity", "data": m})
+}
+
+func (s *Server) handleFollows(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	list := make([]mangadexapi.Manga, 0, len(s.follows))
+	for _, id := range s.follows {
+		list = append(list, s.manga[id])
+	}
+	s.mu.Unlock()
+
+	writeCollection(w, r, list)
+}
+
+func (s *Server) handleIsFollowed(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	followed := false
+	for _, f := range s.follows {
+		followed = followed || f == id
+	}
+	s.mu.Unlock()
+	if !followed {
+		writeError(w, http.StatusNotFound, "not_found_http_exception", "not following")
+		return
+	}
+	writeOK(w)
+}
+
+func (s *Server) handleFollow(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	_, ok := s.manga[id]
+	if ok {
+		s.follow(id)
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found_http_exception", "manga not found")
+		return
+	}
+	writeOK(w)
+}
+
+func (s *Server) handleUnfollow(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	for i, f := range s.follows {
+		if f == id {
+			s.follows = append(s.follows[:i], s.follows[i+1:]...)
+			break
+		}
+	}
+	s.mu.Unlock()
+	writeOK(w)
+}
+
+func (s *Server) handleStatuses(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	statuses := make(map[string]mangadexapi.ReadingStatus, len(s.statuses))
+	for id, st := range s.statuses {
+		statuses[id] = st
+	}
+	s.mu.Unlock()
+
+	var out any = statuses
+	if len(statuses) == 0 {
+		out = []any{} // MangaDex encodes empty maps as arrays
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"result": "ok", "statuses": out})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	st, ok := s.statuses[r.PathValue("id")]
+	s.mu.Unlock()
+	var out any
+	if ok {
+		out = st
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"result": "ok", "status": out})
+}
+
+func (s *Server) handleSetStatus(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Status *mangadexapi.ReadingStatus `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_exception", err.Error())
+		return
+	}
+	id := r.PathValue("id")
+	s.mu.Lock()
+	if body.Status == nil {
+		delete(s.statuses, id)
+	} else {
+		s.statuses[id] = *body.Status
+	}
+	s.mu.Unlock()
+	writeOK(w)
+}
+
+func (s *Server) handleRatings(w http.ResponseWriter, r *http.Request) {
+	out := make(map[string]mangadexapi.Rating)
+	s.mu.Lock()
+	for _, id := range r.URL.Query()["manga[]"] {
+		if v, ok := s.ratings[id]; ok {
+			out[id] = mangadexapi.Rating{Rating: v}
+		}
+	}
+	s.mu.Unlock()
+	var ratings any = out
+	if len(out) == 0 {
+		ratings = []any{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"result": "ok", "ratings": ratings})
+}
+
+func (s *Server) handleSetRating(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Rating int `json:"rating"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Rating < 1 || body.Rating > 10 {
+		writeError(w, http.StatusBadRequest, "validation_exception", "rating must be 1-10")
+		return
+	}
+	s.mu.Lock()
+	s.ratings[r.PathValue("id")] = body.Rating
+	s.mu.Unlock()
+	writeOK(w)
+}
+
+func (s *Server) handleDeleteRating(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	delete(s.ratings, r.PathValue("id"))
+	s.mu.Unlock()
+	writeOK(w)
+}
+
+// writeCollection writes a page of list per the createdAtSince,
+// order[createdAt], limit and offset params.
+func writeCollection(w http.ResponseWriter, r *http.Request, list []mangadexapi.Manga) {
+	q := r.URL.Query()
+	if v := q.Get("createdAtSince"); v != "" {
+		since, err := time.Parse("2006-01-02T15:04:05", v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "validation_exception", "invalid createdAtSince")
+			return
+		}
+		list = slices.DeleteFunc(slices.Clone(list), func(m mangadexapi.Manga) bool {
+			created, _ := time.Parse(time.RFC3339, m.Attributes.CreatedAt)
+			return created.Before(since)
+		})
+	}
+	if q.Get("order[createdAt]") == "asc" {
+		list = slices.Clone(list)
+		slices.SortStableFunc(list, func(a, b mangadexapi.Manga) int {
+			return strings.Compare(a.Attributes.CreatedAt, b.Attributes.CreatedAt)
+		})
+	}
+
+	limit, offset := 10, 0
+	if v, err := strconv.Atoi(q.Get("limit")); err == nil {
+		limit = v
+	}
+	if v, err := strconv.Atoi(q.Get("offset")); err == nil {
+		offset = v
+	}
+	if limit < 0 || limit > 100 || offset < 0 {
+		writeError(w, http.StatusBadRequest, "validation_exception", "invalid limit or offset")
+		return
+	}
+	if offset+limit > maxWindow {
+		writeError(w, http.StatusBadRequest, "validation_exception", "offset + limit must be at most 10000")
+		return
+	}
+
+	page := []mangadexapi.Manga{}
+	if offset < len(list) {
+		page = list[offset:min(offset+limit, len(list))]
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"result":   "ok",
+		"response": "collection",
+		"data":     page,
+		"limit":    limit,
+		"offset":   offset,
+		"total":    len(list),
+	})
+}
+
+func writeOK(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, map[string]string{"result": "ok"})
+}
+
+func writeError(w http.ResponseWriter, status int, title, detail string) {
+	writeJSON(w, status, mangadexapi.Envelope{
+		Result: "error",
+		Errors: []mangadexapi.APIError{{
+			ID:     "fake",
+			Status: status,
+			Title:  title,
+			Detail: detail,
+		}},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Request-ID", "fake-request")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// titleMatches reports whether any title of m contains the folded query.
+func titleMatches(m mangadexapi.Manga, query string) bool {
+	for _, t := range m.Attributes.Title {
+		if strings.Contains(fold(t), query) {
+			return true
+		}
+	}
+	for _, alt := range m.Attributes.AltTitles {
+		for _, t := range alt {
+			if strings.Contains(fold(t), query) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fold lowercases s and keeps only letters, digits and single spaces.
+func fold(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,148 @@
+package mangadexapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Option configures a Client created by NewClient.
+type Option func(*Client)
+
+// WithBaseURL sets the API URL, e.g. "https://api.mangadex.dev" for the
+// sandbox or a local mock server.
+func WithBaseURL(u string) Option {
+	return func(c *Client) { c.baseURL = strings.TrimRight(u, "/") }
+}
+
+// WithAuthURL sets the OAuth token endpoint.
+func WithAuthURL(u string) Option {
+	return func(c *Client) { c.authURL = u }
+}
+
+// WithUserAgent sets the User-Agent header sent with API requests.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// WithHTTPClient uses a copy of hc for API and token requests, so options
+// such as WithTransport and WithTimeout leave hc itself untouched. Apply it
+// before those options, which configure the copy.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		cp := *hc
+		c.httpClient = &cp
+	}
+}
+
+// WithTransport sets the RoundTripper of the HTTP client.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) { c.httpClient.Transport = rt }
+}
+
+// WithTokenDir saves each account's token in dir and reuses it in later runs
+// instead of logging in again; see EnsureToken. Token files are readable by
+// the user only.
+func WithTokenDir(dir string) Option {
+	return func(c *Client) { c.tokenDir = dir }
+}
+
+// WithTimeout limits the duration of each HTTP request.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.httpClient.Timeout = d }
+}
+
+// WithProxy sends requests through the given HTTP(S) proxy. It configures a
+// copy of the current *http.Transport, or of http.DefaultTransport.
+func WithProxy(proxy *url.URL) Option {
+	return func(c *Client) {
+		base, ok := c.httpClient.Transport.(*http.Transport)
+		if !ok {
+			base = http.DefaultTransport.(*http.Transport)
+		}
+		t := base.Clone()
+		t.Proxy = http.ProxyURL(proxy)
+		c.httpClient.Transport = t
+	}
+}
+
+// Default per-client rate limits. MangaDex allows about 5 requests per second
+// per IP; writes and token requests get their own, stricter buckets.
+const (
+	generalRate  = 5
+	generalBurst = 5
+	writeRate    = 2
+	writeBurst   = 2
+	authEvery    = time.Minute // 60 token requests per hour
+	authBurst    = 5
+)
+
+// limiters are the rate buckets a Client waits on.
+type limiters struct {
+	general *rate.Limiter // every API request
+	write   *rate.Limiter // non-GET API requests
+	auth    *rate.Limiter // token requests to the auth server
+	global  *rate.Limiter // optional, shared with other clients
+}
+
+func defaultLimiters() limiters {
+	return limiters{
+		general: rate.NewLimiter(generalRate, generalBurst),
+		write:   rate.NewLimiter(writeRate, writeBurst),
+		auth:    rate.NewLimiter(rate.Every(authEvery), authBurst),
+	}
+}
+
+// WithRateLimiter replaces the client's limiter for all API requests.
+func WithRateLimiter(l *rate.Limiter) Option {
+	return func(c *Client) { c.limits.general = l }
+}
+
+// WithWriteRateLimiter replaces the client's limiter for follow, status,
+// rating and list writes.
+func WithWriteRateLimiter(l *rate.Limiter) Option {
+	return func(c *Client) { c.limits.write = l }
+}
+
+// WithAuthRateLimiter replaces the client's limiter for token requests.
+func WithAuthRateLimiter(l *rate.Limiter) Option {
+	return func(c *Client) { c.limits.auth = l }
+}
+
+// WithGlobalRateLimiter adds a limiter shared between clients, e.g. a
+// process-wide budget for all web sessions. API requests wait on it in
+// addition to the client's own limiters.
+func WithGlobalRateLimiter(l *rate.Limiter) Option {
+	return func(c *Client) { c.limits.global = l }
+}
+
+// waitAPI waits for the buckets of an API request.
+func (c *Client) waitAPI(ctx context.Context, method string) error {
+	ls := []*rate.Limiter{c.limits.global, c.limits.general}
+	if method != http.MethodGet {
+		ls = append(ls, c.limits.write)
+	}
+	return wait(ctx, ls...)
+}
+
+// waitAuth waits for the token request bucket.
+func (c *Client) waitAuth(ctx context.Context) error {
+	return wait(ctx, c.limits.auth)
+}
+
+func wait(ctx context.Context, ls ...*rate.Limiter) error {
+	for _, l := range ls {
+		if l == nil {
+			continue
+		}
+		if err := l.Wait(ctx); err != nil {
+			return fmt.Errorf("rate limit error: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package mangadexapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"time"
+)
+
+// maxResultWindow is the largest offset+limit MangaDex accepts.
+const maxResultWindow = 10000
+
+// pageLimit is the largest page size of collection endpoints.
+const pageLimit = 100
+
+// createdAtSinceLayout is the format of the createdAtSince parameter.
+const createdAtSinceLayout = "2006-01-02T15:04:05"
+
+// ErrResultWindow is returned when a listing is longer than the offset cap
+// allows and cannot be windowed by creation date.
+var ErrResultWindow = errors.New("results past offset 10000 cannot be fetched")
+
+// PageFunc fetches one page of a collection endpoint.
+type PageFunc[T any] func(ctx context.Context, qp QueryParams) ([]T, Stats, error)
+
+// Window lets Paginate get past the offset cap of endpoints that accept
+// createdAtSince and order[createdAt]: once the cap is reached it continues
+// at offset 0 from the creation date of the last item, ordered by creation
+// date.
+type Window[T any] struct {
+	ID        func(T) string
+	CreatedAt func(T) string // RFC 3339
+}
+
+var mangaWindow = &Window[Manga]{
+	ID:        func(m Manga) string { return m.ID },
+	CreatedAt: func(m Manga) string { return m.Attributes.CreatedAt },
+}
+
+// Paginate yields the items of the pages fetched by fetch, starting at qp.
+// It stops after an empty page or once the reported total is reached, and
+// yields a final error if a page fails. With a window, a listing that reaches
+// the offset cap switches to windows ordered by creation date, starting over
+// if qp had no order and skipping the items already yielded; without one, or
+// with another order set, ErrResultWindow ends such listings.
+func Paginate[T any](ctx context.Context, qp QueryParams, fetch PageFunc[T], window *Window[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		if qp.Limit <= 0 || qp.Limit > pageLimit {
+			qp.Limit = pageLimit
+		}
+		byCreation := len(qp.Order) == 1 && qp.Order["createdAt"] == "asc"
+		canWindow := window != nil && (len(qp.Order) == 0 || byCreation)
+
+		var (
+			windowed bool            // paging by createdAtSince windows
+			last     string          // creation time of the last item fetched
+			yielded  map[string]bool // IDs yielded so far, with a window
+		)
+		if canWindow {
+			yielded = make(map[string]bool)
+		}
+		for {
+			if qp.Offset+qp.Limit > maxResultWindow {
+				switch {
+				case canWindow && !windowed && !byCreation:
+					// Start over in creation order.
+					windowed, byCreation = true, true
+					qp.Order = OrderParams{"createdAt": "asc"}
+					qp.Offset = 0
+					last = ""
+				case canWindow:
+					if last == "" || last == qp.CreatedAtSince {
+						yield(zero, fmt.Errorf("%w: more than %d items created at %s", ErrResultWindow, maxResultWindow, last))
+						return
+					}
+					windowed = true
+					qp.CreatedAtSince = last
+					qp.Offset = 0
+				case qp.Offset < maxResultWindow:
+					qp.Limit = maxResultWindow - qp.Offset
+				default:
+					yield(zero, ErrResultWindow)
+					return
+				}
+			}
+
+			page, stats, err := fetch(ctx, qp)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, item := range page {
+				if canWindow {
+					if byCreation {
+						last = sinceFormat(window.CreatedAt(item))
+					}
+					// A new window repeats the items already yielded.
+					id := window.ID(item)
+					if yielded[id] {
+						continue
+					}
+					yielded[id] = true
+				}
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			qp.Offset += len(page)
+			if len(page) == 0 || qp.Offset >= stats.Total {
+				return
+			}
+		}
+	}
+}
+
+// Collect gathers the items of a Paginate sequence.
+func Collect[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var out []T
+	for item, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+// sinceFormat converts an RFC 3339 creation time to the createdAtSince
+// format.
+func sinceFormat(createdAt string) string {
+	t, err := time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return createdAt
+	}
+	return t.UTC().Format(createdAtSinceLayout)
+}
+
+// getPage fetches one page of a collection endpoint.
+func getPage[T any](ctx context.Context, c *Client, endpoint string, qp QueryParams) ([]T, Stats, error) {
+	if err := c.EnsureToken(ctx); err != nil {
+		return nil, Stats{}, err
+	}
+	env, _, err := c.doEnvelope(ctx, http.MethodGet, endpoint, qp.ToValues(), nil)
+	if err != nil {
+		return nil, Stats{}, err
+	}
+	if env == nil || len(env.Data) == 0 { // tolerate empty data
+		return nil, Stats{}, nil
+	}
+	var s Stats
+	if env.Limit != nil {
+		s.Limit = *env.Limit
+	}
+	if env.Offset != nil {
+		s.Offset = *env.Offset
+	}
+	if env.Total != nil {
+		s.Total = *env.Total
+	}
+
+	var list []T
+	if err := decodeData(env.Data, &list); err != nil {
+		return nil, Stats{}, fmt.Errorf("decode data: %w", err)
+	}
+	return list, s, nil
+}
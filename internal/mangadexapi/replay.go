@@ -0,0 +1,237 @@
+package mangadexapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// redacted replaces credentials and tokens in recorded interactions.
+const redacted = "REDACTED"
+
+// scrubbedFields are form and JSON fields never written to a recording.
+var scrubbedFields = []string{"username", "password", "client_id", "client_secret", "refresh_token", "access_token", "id_token"}
+
+// recordedHeaders are the response headers kept in a recording.
+var recordedHeaders = []string{"Content-Type", "X-Request-ID", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Retry-After"}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	RequestBody string      `json:"request_body,omitempty"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        string      `json:"body"`
+}
+
+func (i Interaction) key() string {
+	return i.Method + " " + i.URL + " " + i.RequestBody
+}
+
+// Recorder is an http.RoundTripper that passes requests on and records each
+// interaction with credentials and tokens scrubbed. Install it with
+// WithTransport or WithRecorder and write the recording with Save.
+type Recorder struct {
+	next http.RoundTripper
+	log  *recording
+}
+
+type recording struct {
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+// NewRecorder records requests sent through next, or
+// http.DefaultTransport if nil.
+func NewRecorder(next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{next: next, log: &recording{}}
+}
+
+// WithRecorder records the client's requests into r, sending them through
+// the transport configured so far. Several clients may share one recorder.
+func WithRecorder(r *Recorder) Option {
+	return func(c *Client) {
+		next := c.httpClient.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		c.httpClient.Transport = &Recorder{next: next, log: r.log}
+	}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	in := Interaction{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: scrub(reqBody, req.Header.Get("Content-Type")),
+		Status:      resp.StatusCode,
+		Header:      http.Header{},
+		Body:        scrub(body, resp.Header.Get("Content-Type")),
+	}
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Values(h); len(v) > 0 {
+			in.Header[h] = v
+		}
+	}
+
+	r.log.mu.Lock()
+	r.log.interactions = append(r.log.interactions, in)
+	r.log.mu.Unlock()
+	return resp, nil
+}
+
+// Save writes the recorded interactions to path as JSON.
+func (r *Recorder) Save(path string) error {
+	r.log.mu.Lock()
+	data, err := json.MarshalIndent(r.log.interactions, "", "  ")
+	r.log.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("write recording: %w", err)
+	}
+	return nil
+}
+
+// Replayer is an http.RoundTripper serving a recording made by Recorder.
+// Requests are matched by method, URL and scrubbed body; repeated requests
+// get the recorded responses in order, then the last one again. It never
+// touches the network.
+type Replayer struct {
+	mu     sync.Mutex
+	queues map[string][]Interaction
+}
+
+// LoadReplayer reads a recording written by Recorder.Save.
+func LoadReplayer(path string) (*Replayer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read recording: %w", err)
+	}
+	var interactions []Interaction
+	if err := json.Unmarshal(data, &interactions); err != nil {
+		return nil, fmt.Errorf("decode recording %s: %w", path, err)
+	}
+	r := &Replayer{queues: make(map[string][]Interaction)}
+	for _, in := range interactions {
+		r.queues[in.key()] = append(r.queues[in.key()], in)
+	}
+	return r, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	key := Interaction{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: scrub(reqBody, req.Header.Get("Content-Type")),
+	}.key()
+
+	r.mu.Lock()
+	queue := r.queues[key]
+	if len(queue) == 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("replay: no recorded response for %s %s", req.Method, req.URL)
+	}
+	in := queue[0]
+	if len(queue) > 1 {
+		r.queues[key] = queue[1:]
+	}
+	r.mu.Unlock()
+
+	header := in.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+		StatusCode:    in.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewBufferString(in.Body)),
+		ContentLength: int64(len(in.Body)),
+		Request:       req,
+	}, nil
+}
+
+// scrub redacts credentials and tokens in a form or JSON body.
+func scrub(body []byte, contentType string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return redacted
+		}
+		for _, f := range scrubbedFields {
+			if form.Has(f) {
+				form.Set(f, redacted)
+			}
+		}
+		return form.Encode()
+	}
+
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(body, &obj) != nil {
+		return string(body)
+	}
+	changed := false
+	for _, f := range scrubbedFields {
+		if _, ok := obj[f]; ok {
+			obj[f] = json.RawMessage(`"` + redacted + `"`)
+			changed = true
+		}
+	}
+	if !changed {
+		return string(body)
+	}
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return redacted
+	}
+	return string(out)
+}
@@ -0,0 +1,135 @@
+package mangadexapi
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried. Rate limited (429)
+// responses are always retried after the delay the API asks for; server
+// errors (5xx) and network errors only for requests that are safe to repeat.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first, 1 disables retries
+	BaseDelay   time.Duration // backoff before the second attempt, doubled after each failure
+	MaxDelay    time.Duration // cap for backoff and Retry-After delays
+}
+
+// DefaultRetryPolicy is used by NewClient.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    time.Minute,
+}
+
+// RetryEvent describes a failed attempt that is about to be retried.
+type RetryEvent struct {
+	Method   string
+	Endpoint string
+	Attempt  int           // the attempt that failed, starting at 1
+	Status   int           // HTTP status, 0 for network errors
+	Err      error         // network error, if any
+	Delay    time.Duration // wait before the next attempt
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) { c.retry = p }
+}
+
+// SetRetryObserver installs a callback called before every retry, e.g. to
+// report rate limiting as progress. A nil callback removes it.
+func (c *Client) SetRetryObserver(fn func(RetryEvent)) {
+	c.onRetry = fn
+}
+
+// retryDelay decides whether a failed attempt is retried and after how long.
+// resp is nil for network errors.
+func (p RetryPolicy) retryDelay(method, endpoint string, attempt int, resp *http.Response) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		if d, ok := retryAfter(resp.Header); ok {
+			return min(d, p.MaxDelay), true
+		}
+		return p.backoff(attempt), true
+	}
+	if resp != nil && resp.StatusCode < 500 {
+		return 0, false
+	}
+	if !safeToRetry(method, endpoint) {
+		return 0, false
+	}
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header); ok {
+			return min(d, p.MaxDelay), true
+		}
+	}
+	return p.backoff(attempt), true
+}
+
+// backoff returns a jittered exponential delay for the given failed attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	// Full jitter in [d/2, d) keeps concurrent workers from retrying together.
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half)
+}
+
+// retryAfter reads X-RateLimit-Retry-After (a Unix timestamp) or Retry-After
+// (seconds or an HTTP date).
+func retryAfter(h http.Header) (time.Duration, bool) {
+	if v := h.Get("X-RateLimit-Retry-After"); v != "" {
+		if ts, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return max(time.Until(time.Unix(ts, 0)), 0), true
+		}
+	}
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return max(time.Until(t), 0), true
+		}
+	}
+	return 0, false
+}
+
+// safeToRetry reports whether repeating the request cannot duplicate its
+// effect. Follows, statuses, ratings and list membership set a state and are
+// safe; creating a list is not.
+func safeToRetry(method, endpoint string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		return strings.HasSuffix(endpoint, "/follow") ||
+			strings.HasSuffix(endpoint, "/status") ||
+			strings.HasPrefix(endpoint, "/rating/") ||
+			strings.Contains(endpoint, "/list/")
+	default:
+		return false
+	}
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -1,8 +1,10 @@
 package mangadexapi
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -17,19 +19,50 @@ type AuthForm struct {
 type Token struct {
 	AccessToken  string    `json:"access_token"`
 	RefreshToken string    `json:"refresh_token"`
-	Expiry       time.Time `json:"-"` // don't unmarshal from JSON
+	ExpiresIn    int       `json:"expires_in,omitempty"` // seconds, as sent by the token endpoint
+	Expiry       time.Time `json:"expiry,omitzero"`
 }
 
 // Client is the MangaDex API client.
 type Client struct {
 	httpClient *http.Client
 	baseURL    string
+	authURL    string
 	userAgent  string
 
-	auth  AuthForm
-	token *Token
+	auth     AuthForm
+	tokenMu  sync.Mutex // guards token; held while logging in or refreshing
+	token    *Token
+	tokenDir string
+	limits   limiters
+	retry    RetryPolicy
+	cache    *Cache
+
+	writeHook WriteHook
+	onRetry   func(RetryEvent)
 }
 
+// Write operations passed to a WriteHook.
+const (
+	WriteFollow       = "follow"
+	WriteUnfollow     = "unfollow"
+	WriteStatus       = "status"
+	WriteRating       = "rating"
+	WriteDeleteRating = "delete rating"
+)
+
+// WriteOp describes a follow, status or rating write.
+type WriteOp struct {
+	Kind    string
+	MangaID string
+	Status  ReadingStatus // new status for WriteStatus, empty clears it
+	Rating  int           // new rating for WriteRating
+}
+
+// WriteHook wraps every follow, status and rating write. It must call write
+// to perform the request and return its error.
+type WriteHook func(ctx context.Context, op WriteOp, write func() error) error
+
 // Generic envelope (works for object or collection responses).
 type Envelope struct {
 	Result   string          `json:"result"`             // "ok" or "error"
@@ -70,9 +103,9 @@ type QueryParams struct {
 	// ExcludedOriginalLanguage    []string                  `url:"excludedOriginalLanguage[],omitempty"`
 	// AvailableTranslatedLanguage []string                  `url:"availableTranslatedLanguage[],omitempty"`
 	// PublicationDemographic      []PublicationDemographic  `url:"publicationDemographic[],omitempty"`
-	IDs           []string        `url:"ids[],omitempty"`
-	ContentRating []ContentRating `url:"contentRating[],omitempty"`
-	// CreatedAtSince              string                    `url:"createdAtSince,omitempty"`
+	IDs            []string        `url:"ids[],omitempty"`
+	ContentRating  []ContentRating `url:"contentRating[],omitempty"`
+	CreatedAtSince string          `url:"createdAtSince,omitempty"` // "2006-01-02T15:04:05", UTC
 	// UpdatedAtSince              string                    `url:"updatedAtSince,omitempty"`
 	Includes []ReferenceExpansionManga `url:"includes[],omitempty"`
 	// HasAvailableChapters        HasAvailableChapters      `url:"hasAvailableChapters,omitempty"`
@@ -86,10 +119,29 @@ type OrderParams map[string]string // e.g. {"title": "asc", "latestUploadedChapt
 
 // Manga represents a manga object from the MangaDex API.
 type Manga struct {
-	ID string `json:"id"`
-	// Type       string          `json:"type"`
-	Attributes MangaAttributes `json:"attributes"`
-	// Relationships []Relationship  `json:"relationships"`
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	Attributes    MangaAttributes `json:"attributes"`
+	Relationships []Relationship  `json:"relationships"`
+}
+
+// URL returns the manga's page on mangadex.org.
+func (m Manga) URL() string {
+	return "https://mangadex.org/title/" + m.ID
+}
+
+// CoverURL returns a 256px cover thumbnail URL, or "" when the cover_art
+// relationship was not expanded (see RefExpCoverArt).
+func (m Manga) CoverURL() string {
+	for _, rel := range m.Relationships {
+		if rel.Type != "cover_art" {
+			continue
+		}
+		if fn, ok := rel.Attributes["fileName"].(string); ok && fn != "" {
+			return "https://uploads.mangadex.org/covers/" + m.ID + "/" + fn + ".256.jpg"
+		}
+	}
+	return ""
 }
 
 // MangaAttributes represents the attributes of a manga.
@@ -98,10 +150,10 @@ type MangaAttributes struct {
 	AltTitles []map[string]string `json:"altTitles"`
 	//	Description                    map[string]string      `json:"description"`
 	//	IsLocked                       bool                   `json:"isLocked"`
-	// Links map[string]string `json:"links"`
-	//	OriginalLanguage               string                 `json:"originalLanguage"`
+	Links            map[string]string `json:"links"`
+	OriginalLanguage string            `json:"originalLanguage"`
 	//	LastVolume                     string                 `json:"lastVolume"`
-	//	LastChapter                    string                 `json:"lastChapter"`
+	LastChapter string `json:"lastChapter"`
 	//	PublicationDemographic         PublicationDemographic `json:"publicationDemographic"`
 	//	Status                         Status                 `json:"status"`
 	//	Year                           int                    `json:"year"`
@@ -109,10 +161,10 @@ type MangaAttributes struct {
 	//	ChapterNumbersResetOnNewVolume bool                   `json:"chapterNumbersResetOnNewVolume"`
 	//	AvailableTranslatedLanguages   []string               `json:"availableTranslatedLanguages"`
 	//	LatestUploadedChapter          string                 `json:"latestUploadedChapter"`
-	// Tags []Tag `json:"tags"`
+	Tags []Tag `json:"tags"`
 	// State                          string                 `json:"state"`
 	// Version                        int                    `json:"version"`
-	// CreatedAt                      string                 `json:"createdAt"`
+	CreatedAt string `json:"createdAt"`
 	// UpdatedAt                      string                 `json:"updatedAt"`
 }
 
@@ -209,6 +261,65 @@ const (
 	RefExpCreator  ReferenceExpansionManga = "creator"
 )
 
+// CustomList represents a user's custom list of manga.
+type CustomList struct {
+	ID            string               `json:"id"`
+	Type          string               `json:"type"`
+	Attributes    CustomListAttributes `json:"attributes"`
+	Relationships []Relationship       `json:"relationships"`
+}
+
+// CustomListAttributes represents the attributes of a custom list.
+type CustomListAttributes struct {
+	Name       string `json:"name"`
+	Visibility string `json:"visibility"` // "public" or "private"
+	Version    int    `json:"version"`
+}
+
+// MangaIDs returns the IDs of the manga in the list.
+func (l CustomList) MangaIDs() []string {
+	var ids []string
+	for _, rel := range l.Relationships {
+		if rel.Type == "manga" {
+			ids = append(ids, rel.ID)
+		}
+	}
+	return ids
+}
+
+// Chapter represents a chapter object from the MangaDex API.
+type Chapter struct {
+	ID            string            `json:"id"`
+	Type          string            `json:"type"`
+	Attributes    ChapterAttributes `json:"attributes"`
+	Relationships []Relationship    `json:"relationships"`
+}
+
+// ChapterAttributes represents the attributes of a chapter.
+type ChapterAttributes struct {
+	Volume             string `json:"volume"`
+	Chapter            string `json:"chapter"`
+	Title              string `json:"title"`
+	TranslatedLanguage string `json:"translatedLanguage"`
+	CreatedAt          string `json:"createdAt"`
+}
+
+// MangaID returns the ID of the manga the chapter belongs to.
+func (ch Chapter) MangaID() string {
+	for _, rel := range ch.Relationships {
+		if rel.Type == "manga" {
+			return rel.ID
+		}
+	}
+	return ""
+}
+
+// Rating is a user's rating of a manga (1-10).
+type Rating struct {
+	Rating    int    `json:"rating"`
+	CreatedAt string `json:"createdAt"`
+}
+
 // ReadingStatus for user manga reading status
 type ReadingStatus string
 
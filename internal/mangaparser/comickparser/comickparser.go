@@ -11,17 +11,18 @@ import (
 // Manga represents a single row from the comick CSV export.
 // Fields are exported so callers can read them.
 type Manga struct {
-	// HID          string   // hid column
-	Title string // title column
-	// Type         string   // type column (Manga/Manhwa/etc)
-	// Rating       string   // rating column (kept as string to preserve whatever format)
-	// Origination  string   // origination column
-	// Read         string   // read column (kept as string to preserve whatever format)
-	// LastRead     string   // last_read column
-	// Synonyms []string // parsed synonyms (split on comma/semicolon/pipe)
-	// MAL          string   // myanimelist url/id column
-	// AniList      string   // anilist url/id column
-	// MangaUpdates string   // mangaupdates url/id column
+	Row          int      // 1-based data row (header excluded)
+	HID          string   // hid column
+	Title        string   // title column
+	Type         string   // type column (Manga/Manhwa/etc)
+	Rating       string   // rating column (kept as string to preserve whatever format)
+	Origination  string   // origination column
+	Read         string   // read column (kept as string to preserve whatever format)
+	LastRead     string   // last_read column
+	Synonyms     []string // parsed synonyms (split on comma/semicolon/pipe)
+	MAL          string   // myanimelist url/id column
+	AniList      string   // anilist url/id column
+	MangaUpdates string   // mangaupdates url/id column
 }
 
 // ParseComickFile reads the CSV at filePath and returns a slice of Manga.
@@ -35,7 +36,7 @@ type Manga struct {
 // skipped.
 
 // ParseComickFile parses a Comick CSV file from disk (original)
-func ParseComickFile(path string) ([]string, error) {
+func ParseComickFile(path string) ([]Manga, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("open file: %w", err)
@@ -46,7 +47,7 @@ func ParseComickFile(path string) ([]string, error) {
 }
 
 // ParseComickReader parses Comick CSV data from any io.Reader
-func ParseComickReader(reader io.Reader) ([]string, error) {
+func ParseComickReader(reader io.Reader) ([]Manga, error) {
 	r := csv.NewReader(reader)
 	// Read header row (required for mapping). If EOF, return empty slice.
 	header, err := r.Read()
@@ -60,7 +61,7 @@ func ParseComickReader(reader io.Reader) ([]string, error) {
 	// Build header map with normalized names
 	headerMap := make(map[string]int, len(header))
 	for i, h := range header {
-		headerMap[h] = i
+		headerMap[normalizeHeader(h)] = i
 	}
 
 	// Read remaining records
@@ -71,17 +72,17 @@ func ParseComickReader(reader io.Reader) ([]string, error) {
 
 	// Helper: get index for a canonical name, fallback to default indices
 	defaults := map[string]int{
-		// "hid":          0,
-		"title": 1,
-		// "type":         2,
-		// "rating":       3,
-		// "origination":  4,
-		// "read":         5,
-		// "last_read":    6,
-		// "synonyms": 7,
-		// "mal":          8,
-		// "anilist":      9,
-		// "mangaupdates": 10,
+		"hid":          0,
+		"title":        1,
+		"type":         2,
+		"rating":       3,
+		"origination":  4,
+		"read":         5,
+		"last_read":    6,
+		"synonyms":     7,
+		"mal":          8,
+		"anilist":      9,
+		"mangaupdates": 10,
 	}
 
 	getIndex := func(name string) int {
@@ -94,35 +95,34 @@ func ParseComickReader(reader io.Reader) ([]string, error) {
 		return -1
 	}
 
-	// hidIdx := getIndex("hid")
+	hidIdx := getIndex("hid")
 	titleIdx := getIndex("title")
-	// typeIdx := getIndex("type")
-	// ratingIdx := getIndex("rating")
-	// origIdx := getIndex("origination")
-	// readIdx := getIndex("read")
-	// lastReadIdx := getIndex("last_read")
-	// synIdx := getIndex("synonyms")
-	// malIdx := getIndex("mal")
-	// aniIdx := getIndex("anilist")
-	// muIdx := getIndex("mangaupdates")
-
-	/*
-		splitSynonyms := func(s string) []string {
-			s = strings.TrimSpace(s)
-			if s == "" {
-				return nil
-			}
-			var parts []string
-			parts = strings.Split(s, ",")
-			out := make([]string, 0, len(parts))
-			for _, p := range parts {
-				if t := strings.TrimSpace(p); t != "" {
-					out = append(out, t)
-				}
+	typeIdx := getIndex("type")
+	ratingIdx := getIndex("rating")
+	origIdx := getIndex("origination")
+	readIdx := getIndex("read")
+	lastReadIdx := getIndex("last_read")
+	synIdx := getIndex("synonyms")
+	malIdx := getIndex("mal")
+	aniIdx := getIndex("anilist")
+	muIdx := getIndex("mangaupdates")
+
+	splitSynonyms := func(s string) []string {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return nil
+		}
+		parts := strings.FieldsFunc(s, func(r rune) bool {
+			return r == ',' || r == ';' || r == '|'
+		})
+		out := make([]string, 0, len(parts))
+		for _, p := range parts {
+			if t := strings.TrimSpace(p); t != "" {
+				out = append(out, t)
 			}
-			return out
 		}
-	*/
+		return out
+	}
 
 	get := func(rec []string, idx int) string {
 		if idx < 0 || idx >= len(rec) {
@@ -131,8 +131,8 @@ func ParseComickReader(reader io.Reader) ([]string, error) {
 		return strings.TrimSpace(rec[idx])
 	}
 
-	var out []string
-	for _, rec := range records {
+	var out []Manga
+	for i, rec := range records {
 		// Skip completely empty records
 		if len(rec) == 0 {
 			continue
@@ -144,27 +144,56 @@ func ParseComickReader(reader io.Reader) ([]string, error) {
 			continue
 		}
 
-		/*
-			m := Manga{
-				// HID:          get(rec, hidIdx),
-				Title: title,
-				// Type:         get(rec, typeIdx),
-				// Rating:       get(rec, ratingIdx),
-				// Origination:  get(rec, origIdx),
-				// Read:         get(rec, readIdx),
-				// LastRead:     get(rec, lastReadIdx),
-				// Synonyms: splitSynonyms(get(rec, synIdx)),
-				// MAL:          get(rec, malIdx),
-				// AniList:      get(rec, aniIdx),
-				// MangaUpdates: get(rec, muIdx),
-			}
-		*/
-		out = append(out, title)
+		out = append(out, Manga{
+			Row:          i + 1,
+			HID:          get(rec, hidIdx),
+			Title:        title,
+			Type:         get(rec, typeIdx),
+			Rating:       get(rec, ratingIdx),
+			Origination:  get(rec, origIdx),
+			Read:         get(rec, readIdx),
+			LastRead:     get(rec, lastReadIdx),
+			Synonyms:     splitSynonyms(get(rec, synIdx)),
+			MAL:          get(rec, malIdx),
+			AniList:      get(rec, aniIdx),
+			MangaUpdates: get(rec, muIdx),
+		})
 	}
 
 	return out, nil
 }
 
+// Header is the column layout written by WriteComick.
+var Header = []string{"hid", "title", "type", "rating", "origination", "read", "last_read", "synonyms", "mal", "anilist", "mangaupdates"}
+
+// WriteComick writes rows in the comick export layout so the output can be
+// parsed again by ParseComickReader.
+func WriteComick(w io.Writer, rows []Manga) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(Header); err != nil {
+		return err
+	}
+	for _, m := range rows {
+		if err := cw.Write([]string{
+			m.HID,
+			m.Title,
+			m.Type,
+			m.Rating,
+			m.Origination,
+			m.Read,
+			m.LastRead,
+			strings.Join(m.Synonyms, ", "),
+			m.MAL,
+			m.AniList,
+			m.MangaUpdates,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
 // normalizeHeader converts header string to a normalized canonical form used
 // for comparison: lowercased, trimmed, spaces -> underscore, and common
 // punctuation removed. This helps match headers like "Last Read" and "last_read".
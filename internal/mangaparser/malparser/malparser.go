@@ -13,9 +13,13 @@ type MALData struct {
 }
 
 type Manga struct {
-	ID       int    `xml:"manga_mangadb_id"`
-	Title    string `xml:"manga_title"`
-	MyStatus string `xml:"my_status"`
+	ID             int    `xml:"manga_mangadb_id"`
+	Title          string `xml:"manga_title"`
+	Chapters       int    `xml:"manga_chapters"`
+	MyReadChapters int    `xml:"my_read_chapters"`
+	MyScore        int    `xml:"my_score"`
+	MyStatus       string `xml:"my_status"`
+	MyLastUpdated  int64  `xml:"my_last_updated,omitempty"` // unix seconds
 }
 
 func ParseMALFile(path string) (*MALData, error) {
@@ -50,3 +54,23 @@ func ReturnMALTitles(manga *MALData) []string {
 	}
 	return titles
 }
+
+// WriteMAL writes entries as a MAL export (the subset of fields in Manga),
+// readable by ParseMALReader and MAL's own importer.
+func WriteMAL(w io.Writer, entries []Manga) error {
+	doc := struct {
+		XMLName xml.Name `xml:"myanimelist"`
+		Entries []Manga  `xml:"manga"`
+	}{Entries: entries}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("encode xml: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -4,13 +4,82 @@ import (
 	"bytes"
 	"fmt"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Another0Noob/mangadex-import/internal/mangaparser/comickparser"
 	"github.com/Another0Noob/mangadex-import/internal/mangaparser/malparser"
 )
 
-func Parse(path string) ([]string, error) {
+// Status is a reading status normalized across import formats. Values mirror
+// MangaDex reading statuses so they can be applied directly.
+type Status string
+
+const (
+	StatusNone       Status = ""
+	StatusReading    Status = "reading"
+	StatusOnHold     Status = "on_hold"
+	StatusPlanToRead Status = "plan_to_read"
+	StatusDropped    Status = "dropped"
+	StatusReReading  Status = "re_reading"
+	StatusCompleted  Status = "completed"
+)
+
+// statusRank orders statuses from least to most progressed; used to keep the
+// "best" status when entries are merged.
+var statusRank = map[Status]int{
+	StatusNone:       0,
+	StatusPlanToRead: 1,
+	StatusDropped:    2,
+	StatusOnHold:     3,
+	StatusReading:    4,
+	StatusReReading:  5,
+	StatusCompleted:  6,
+}
+
+// Better reports whether s is further along than other.
+func (s Status) Better(other Status) bool {
+	return statusRank[s] > statusRank[other]
+}
+
+// Format identifies an import/export file format.
+type Format string
+
+const (
+	FormatMAL    Format = "mal"    // MAL XML export
+	FormatComick Format = "comick" // Comick CSV export
+	FormatList   Format = "list"   // plain text, one title per line
+)
+
+// Entry is a single import row, independent of the source format.
+type Entry struct {
+	Title       string
+	Synonyms    []string
+	ExternalIDs []string // e.g. "mal:123", "comick:abc"; used for de-duplication
+	Status      Status
+	Progress    float64   // chapters read
+	Rating      int       // 0 (unrated) to 10
+	UpdatedAt   time.Time // last change in the source list, zero if unknown
+	Rows        []int     // 1-based source rows merged into this entry
+	Format      Format    // format the entry was parsed from, if any
+
+	// original records, kept so entries can be written back unchanged
+	mal    *malparser.Manga
+	comick *comickparser.Manga
+}
+
+// Titles returns the title of every entry, in order.
+func Titles(entries []Entry) []string {
+	titles := make([]string, len(entries))
+	for i, e := range entries {
+		titles[i] = e.Title
+	}
+	return titles
+}
+
+func Parse(path string) ([]Entry, error) {
 	ext := strings.ToLower(filepath.Ext(path))
 
 	switch ext {
@@ -19,20 +88,20 @@ func Parse(path string) ([]string, error) {
 		if err != nil {
 			return nil, err
 		}
-		return out, nil
+		return fromComick(out), nil
 	case ".xml":
 		out, err := malparser.ParseMALFile(path)
 		if err != nil {
 			return nil, err
 		}
-		return malparser.ReturnMALTitles(out), nil
+		return fromMAL(out), nil
 	default:
 		return nil, fmt.Errorf("unknown file format: %s (must be .csv or .xml)", ext)
 	}
 }
 
 // ParseFromBytes parses file content directly from memory
-func ParseFromBytes(data []byte, filename string) ([]string, error) {
+func ParseFromBytes(data []byte, filename string) ([]Entry, error) {
 	ext := strings.ToLower(filepath.Ext(filename))
 	reader := bytes.NewReader(data)
 
@@ -42,14 +111,110 @@ func ParseFromBytes(data []byte, filename string) ([]string, error) {
 		if err != nil {
 			return nil, err
 		}
-		return out, nil
+		return fromComick(out), nil
 	case ".xml":
 		out, err := malparser.ParseMALReader(reader)
 		if err != nil {
 			return nil, err
 		}
-		return malparser.ReturnMALTitles(out), nil
+		return fromMAL(out), nil
 	default:
 		return nil, fmt.Errorf("unknown file format: %s (must be .csv or .xml)", ext)
 	}
 }
+
+func fromMAL(data *malparser.MALData) []Entry {
+	entries := make([]Entry, len(data.Entries))
+	for i, m := range data.Entries {
+		e := Entry{
+			Title:    m.Title,
+			Status:   ParseStatus(m.MyStatus),
+			Progress: float64(m.MyReadChapters),
+			Rating:   m.MyScore,
+			Rows:     []int{i + 1},
+			Format:   FormatMAL,
+			mal:      &data.Entries[i],
+		}
+		if m.MyLastUpdated > 0 {
+			e.UpdatedAt = time.Unix(m.MyLastUpdated, 0)
+		}
+		if m.ID > 0 {
+			e.ExternalIDs = append(e.ExternalIDs, "mal:"+strconv.Itoa(m.ID))
+		}
+		entries[i] = e
+	}
+	return entries
+}
+
+func fromComick(rows []comickparser.Manga) []Entry {
+	entries := make([]Entry, len(rows))
+	for i, m := range rows {
+		e := Entry{
+			Title:    m.Title,
+			Synonyms: m.Synonyms,
+			Status:   ParseStatus(m.Type),
+			Rows:     []int{m.Row},
+			Format:   FormatComick,
+			comick:   &rows[i],
+		}
+		if p, err := strconv.ParseFloat(m.Read, 64); err == nil {
+			e.Progress = p
+		}
+		if r, err := strconv.ParseFloat(m.Rating, 64); err == nil {
+			e.Rating = int(r + 0.5)
+		}
+		if m.HID != "" {
+			e.ExternalIDs = append(e.ExternalIDs, "comick:"+m.HID)
+		}
+		if id := siteID(m.MAL); id != "" {
+			e.ExternalIDs = append(e.ExternalIDs, "mal:"+id)
+		}
+		if id := siteID(m.AniList); id != "" {
+			e.ExternalIDs = append(e.ExternalIDs, "anilist:"+id)
+		}
+		if id := siteID(m.MangaUpdates); id != "" {
+			e.ExternalIDs = append(e.ExternalIDs, "mu:"+id)
+		}
+		entries[i] = e
+	}
+	return entries
+}
+
+var reSiteID = regexp.MustCompile(`/(?:manga|series)/([A-Za-z0-9]+)`)
+
+// siteID extracts the ID from either a bare ID or a site URL such as
+// https://myanimelist.net/manga/2/Berserk or https://www.mangaupdates.com/series/abc123.
+func siteID(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	if m := reSiteID.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	if strings.ContainsAny(s, "/:") {
+		return ""
+	}
+	return s
+}
+
+// ParseStatus maps the status spellings used by MAL and Comick exports to a
+// Status. Unknown values map to StatusNone.
+func ParseStatus(s string) Status {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "reading", "1":
+		return StatusReading
+	case "completed", "2":
+		return StatusCompleted
+	case "on-hold", "on hold", "on_hold", "3":
+		return StatusOnHold
+	case "dropped", "4":
+		return StatusDropped
+	case "plan to read", "plan_to_read", "6":
+		return StatusPlanToRead
+	case "re-reading", "rereading", "re_reading":
+		return StatusReReading
+	default:
+		return StatusNone
+	}
+}
@@ -0,0 +1,170 @@
+package mangaparser
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangaparser/comickparser"
+	"github.com/Another0Noob/mangadex-import/internal/mangaparser/malparser"
+)
+
+// FormatFromPath picks the output format from a file extension.
+func FormatFromPath(path string) (Format, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".xml":
+		return FormatMAL, nil
+	case ".csv":
+		return FormatComick, nil
+	case ".txt":
+		return FormatList, nil
+	default:
+		return "", fmt.Errorf("unknown file format: %s (must be .xml, .csv or .txt)", ext)
+	}
+}
+
+// Extension returns the file extension used for f.
+func (f Format) Extension() string {
+	switch f {
+	case FormatMAL:
+		return ".xml"
+	case FormatComick:
+		return ".csv"
+	default:
+		return ".txt"
+	}
+}
+
+// Write writes entries in the given format. Entries parsed from the same
+// format are written back from their original record; others are converted
+// from the generic Entry fields.
+func Write(w io.Writer, format Format, entries []Entry) error {
+	switch format {
+	case FormatMAL:
+		out := make([]malparser.Manga, len(entries))
+		for i, e := range entries {
+			out[i] = ToMAL(e)
+		}
+		return malparser.WriteMAL(w, out)
+	case FormatComick:
+		out := make([]comickparser.Manga, len(entries))
+		for i, e := range entries {
+			out[i] = ToComick(e)
+		}
+		return comickparser.WriteComick(w, out)
+	case FormatList:
+		for _, e := range entries {
+			if _, err := fmt.Fprintln(w, e.Title); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// WriteFile writes entries to path in the format matching its extension.
+func WriteFile(path string, entries []Entry) error {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+	defer file.Close()
+
+	if err := Write(file, format, entries); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// ToMAL converts an entry to a MAL export row. Entries parsed from MAL keep
+// their original record, with status, progress and rating updated if changed.
+func ToMAL(e Entry) malparser.Manga {
+	if e.mal != nil {
+		m := *e.mal
+		if e.Status != ParseStatus(m.MyStatus) {
+			m.MyStatus = MALStatus(e.Status)
+		}
+		m.MyReadChapters = int(e.Progress)
+		m.MyScore = e.Rating
+		return m
+	}
+	m := malparser.Manga{
+		Title:          e.Title,
+		MyReadChapters: int(e.Progress),
+		MyScore:        e.Rating,
+		MyStatus:       MALStatus(e.Status),
+	}
+	if !e.UpdatedAt.IsZero() {
+		m.MyLastUpdated = e.UpdatedAt.Unix()
+	}
+	if id, err := strconv.Atoi(ExternalID(e, "mal")); err == nil {
+		m.ID = id
+	}
+	return m
+}
+
+// ToComick converts an entry to a comick export row. Entries parsed from
+// comick keep their original record, with the status updated if changed.
+func ToComick(e Entry) comickparser.Manga {
+	if e.comick != nil {
+		m := *e.comick
+		if e.Status != ParseStatus(m.Type) {
+			m.Type = MALStatus(e.Status)
+		}
+		return m
+	}
+	m := comickparser.Manga{
+		HID:          ExternalID(e, "comick"),
+		Title:        e.Title,
+		Type:         MALStatus(e.Status),
+		Synonyms:     e.Synonyms,
+		MAL:          ExternalID(e, "mal"),
+		AniList:      ExternalID(e, "anilist"),
+		MangaUpdates: ExternalID(e, "mu"),
+	}
+	if e.Progress > 0 {
+		m.Read = strconv.FormatFloat(e.Progress, 'f', -1, 64)
+	}
+	if e.Rating > 0 {
+		m.Rating = strconv.Itoa(e.Rating)
+	}
+	return m
+}
+
+// ExternalID returns the entry's ID on the given site ("mal", "comick", ...).
+func ExternalID(e Entry, site string) string {
+	for _, id := range e.ExternalIDs {
+		if v, ok := strings.CutPrefix(id, site+":"); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// MALStatus returns the status spelling used in MAL (and comick) exports.
+func MALStatus(s Status) string {
+	switch s {
+	case StatusReading, StatusReReading:
+		return "Reading"
+	case StatusCompleted:
+		return "Completed"
+	case StatusOnHold:
+		return "On-Hold"
+	case StatusDropped:
+		return "Dropped"
+	case StatusPlanToRead:
+		return "Plan to Read"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,144 @@
+package match
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+)
+
+// Alternative is a MangaDex entry that shared a title with the chosen match
+// but was judged less canonical (colored edition, doujinshi, oneshot, ...).
+type Alternative struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Reason string `json:"reason"`
+}
+
+// derivativeTags mark entries that are editions or spin-offs of another work.
+var derivativeTags = map[string]int{
+	"doujinshi":        4,
+	"fan colored":      3,
+	"official colored": 2,
+	"oneshot":          1,
+	"anthology":        1,
+}
+
+// derivedRelations are `related` values which, when set on entry A pointing to
+// entry B, mean B is derived from A.
+var derivedRelations = map[string]string{
+	"colored":           "colored version",
+	"doujinshi":         "doujinshi",
+	"side_story":        "side story",
+	"spin_off":          "spin-off",
+	"alternate_version": "alternate version",
+}
+
+// sourceRelations are `related` values which, when set on entry A pointing to
+// entry B, mean A is derived from B.
+var sourceRelations = map[string]string{
+	"monochrome":   "colored version",
+	"main_story":   "side story",
+	"based_on":     "derivative",
+	"adapted_from": "adaptation",
+}
+
+type canonicalScore struct {
+	idx     int
+	penalty int
+	reasons []string
+	chap    float64
+}
+
+// pickCanonical chooses the most canonical entry among candidates that share
+// a title. It returns the index of the chosen entry and the remaining entries
+// as alternatives. Scoring uses content tags, relationship data between the
+// candidates, originalLanguage and chapter count; ties keep search order.
+func pickCanonical(cands []mangadexapi.Manga) (int, []Alternative) {
+	if len(cands) == 0 {
+		return -1, nil
+	}
+	if len(cands) == 1 {
+		return 0, nil
+	}
+
+	byID := make(map[string]int, len(cands))
+	langCount := make(map[string]int)
+	for i, m := range cands {
+		byID[m.ID] = i
+		if l := m.Attributes.OriginalLanguage; l != "" {
+			langCount[l]++
+		}
+	}
+	majorityLang := ""
+	for l, n := range langCount {
+		if n > langCount[majorityLang] || (n == langCount[majorityLang] && l < majorityLang) {
+			majorityLang = l
+		}
+	}
+
+	scores := make([]canonicalScore, len(cands))
+	for i, m := range cands {
+		scores[i].idx = i
+		scores[i].chap, _ = strconv.ParseFloat(m.Attributes.LastChapter, 64)
+
+		for _, t := range m.Attributes.Tags {
+			name := strings.ToLower(t.Attributes.Name["en"])
+			if p, ok := derivativeTags[name]; ok {
+				scores[i].penalty += p
+				scores[i].reasons = append(scores[i].reasons, name)
+			}
+		}
+
+		if majorityLang != "" && m.Attributes.OriginalLanguage != "" && m.Attributes.OriginalLanguage != majorityLang {
+			scores[i].penalty++
+			scores[i].reasons = append(scores[i].reasons, "original language "+m.Attributes.OriginalLanguage)
+		}
+	}
+
+	// Relationships only count when both ends are among the candidates.
+	for i, m := range cands {
+		for _, rel := range m.Relationships {
+			if rel.Type != "manga" {
+				continue
+			}
+			j, ok := byID[rel.ID]
+			if !ok || j == i {
+				continue
+			}
+			if reason, ok := derivedRelations[rel.Related]; ok {
+				scores[j].penalty += 2
+				scores[j].reasons = append(scores[j].reasons, reason+" of "+cands[i].ID)
+			}
+			if reason, ok := sourceRelations[rel.Related]; ok {
+				scores[i].penalty += 2
+				scores[i].reasons = append(scores[i].reasons, reason+" of "+cands[j].ID)
+			}
+		}
+	}
+
+	order := make([]canonicalScore, len(scores))
+	copy(order, scores)
+	sort.SliceStable(order, func(a, b int) bool {
+		if order[a].penalty != order[b].penalty {
+			return order[a].penalty < order[b].penalty
+		}
+		return order[a].chap > order[b].chap
+	})
+
+	best := order[0].idx
+	alts := make([]Alternative, 0, len(order)-1)
+	for _, s := range order[1:] {
+		reason := strings.Join(s.reasons, ", ")
+		if reason == "" {
+			reason = "same title"
+		}
+		alts = append(alts, Alternative{
+			ID:     cands[s.idx].ID,
+			Title:  pickOriginalTitle(cands[s.idx]),
+			Reason: reason,
+		})
+	}
+	return best, alts
+}
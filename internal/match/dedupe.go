@@ -0,0 +1,112 @@
+package match
+
+import (
+	"github.com/Another0Noob/mangadex-import/internal/mangaparser"
+)
+
+// Duplicate describes import rows that were merged into a single entry.
+type Duplicate struct {
+	Title  string `json:"title"`  // title of the kept entry
+	Rows   []int  `json:"rows"`   // source rows merged, in input order
+	Reason string `json:"reason"` // "external id" or "title"
+}
+
+// DedupeEntries merges import entries describing the same series. Entries are
+// considered equal when they share an external ID, or failing that when their
+// titles normalize to the same string. Merged entries keep the first title,
+// the highest progress and the most advanced status.
+func DedupeEntries(entries []mangaparser.Entry) ([]mangaparser.Entry, []Duplicate) {
+	out := make([]mangaparser.Entry, 0, len(entries))
+	byKey := make(map[string]int) // "id:<ext>" or "title:<normalized>" -> index in out
+	reasons := make(map[int]string)
+
+	register := func(i int) {
+		for _, id := range out[i].ExternalIDs {
+			byKey["id:"+id] = i
+		}
+		if n := NormalizeTitle(out[i].Title); n != "" {
+			byKey["title:"+n] = i
+		}
+	}
+
+	for _, e := range entries {
+		idx, reason := -1, ""
+		for _, id := range e.ExternalIDs {
+			if i, ok := byKey["id:"+id]; ok {
+				idx, reason = i, "external id"
+				break
+			}
+		}
+		if idx < 0 {
+			if n := NormalizeTitle(e.Title); n != "" {
+				if i, ok := byKey["title:"+n]; ok {
+					idx, reason = i, "title"
+				}
+			}
+		}
+
+		if idx < 0 {
+			out = append(out, e)
+			register(len(out) - 1)
+			continue
+		}
+
+		out[idx] = mergeEntries(out[idx], e)
+		if _, ok := reasons[idx]; !ok {
+			reasons[idx] = reason
+		}
+		register(idx)
+	}
+
+	var dups []Duplicate
+	for i, e := range out {
+		if len(e.Rows) < 2 {
+			continue
+		}
+		dups = append(dups, Duplicate{
+			Title:  e.Title,
+			Rows:   e.Rows,
+			Reason: reasons[i],
+		})
+	}
+	return out, dups
+}
+
+// mergeEntries folds b into a, keeping a's title.
+func mergeEntries(a, b mangaparser.Entry) mangaparser.Entry {
+	a.Rows = append(append([]int(nil), a.Rows...), b.Rows...)
+	a.ExternalIDs = appendUnique(a.ExternalIDs, b.ExternalIDs...)
+	a.Synonyms = appendUnique(a.Synonyms, b.Synonyms...)
+	if b.Title != a.Title {
+		a.Synonyms = appendUnique(a.Synonyms, b.Title)
+	}
+	if b.Progress > a.Progress {
+		a.Progress = b.Progress
+	}
+	if b.Status.Better(a.Status) {
+		a.Status = b.Status
+	}
+	if a.Rating == 0 {
+		a.Rating = b.Rating
+	}
+	if b.UpdatedAt.After(a.UpdatedAt) {
+		a.UpdatedAt = b.UpdatedAt
+	}
+	return a
+}
+
+func appendUnique(dst []string, vals ...string) []string {
+	seen := make(map[string]struct{}, len(dst))
+	for _, v := range dst {
+		seen[v] = struct{}{}
+	}
+	out := append([]string(nil), dst...)
+	for _, v := range vals {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		out = append(out, v)
+	}
+	return out
+}
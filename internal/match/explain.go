@@ -0,0 +1,187 @@
+package match
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+	"github.com/lithammer/fuzzysearch/fuzzy"
+)
+
+// FuzzyCandidate is a normalized title that survived filterCandidates.
+type FuzzyCandidate struct {
+	Title    string
+	Distance int      // -1 when the pattern is not a fuzzy match of Title
+	IDs      []string // MangaDex IDs owning Title
+}
+
+// SearchCandidate is a single /manga search result.
+type SearchCandidate struct {
+	ID         string
+	Title      string
+	ExactTitle bool // one of its titles normalizes to the import title
+}
+
+// Explanation describes every step of matching a single import title.
+type Explanation struct {
+	Input      string
+	Normalized string
+	Rules      []NormalizeStep
+
+	// Direct stage, against the user's follows
+	MainHits []string // IDs found in MainTitleIndex
+	AltHits  []string // IDs found in AltTitleIndex
+	Owners   []string // all IDs owning the normalized title
+
+	// Fuzzy stage, against the user's follows
+	Threshold       int
+	FuzzyCandidates []FuzzyCandidate
+
+	// Search stage
+	Searched      bool
+	SearchQuery   string
+	SearchResults []SearchCandidate
+	SearchError   error
+
+	Rejections   []string // why each stage that ran did not match
+	Stage        string   // "direct", "fuzzy", "search" or "" when unmatched
+	ChosenID     string
+	ChosenTitle  string
+	Reason       string // why the final choice won
+	Alternatives []Alternative
+}
+
+// Explain runs the direct, fuzzy and search stages for a single title and
+// records why each stage accepted or rejected it. The search stage only runs
+// when the title did not match the user's follows, mirroring runFollow.
+// Matches taken by other import entries are not considered.
+func Explain(ctx context.Context, client *mangadexapi.Client, followed []mangadexapi.Manga, title string) *Explanation {
+	ex := &Explanation{Input: title}
+	ex.Normalized, ex.Rules = NormalizeTitleTrace(title)
+	if ex.Normalized == "" {
+		ex.reject("title is empty after normalization")
+		return ex
+	}
+
+	idx := BuildFollowedIndexes(followed)
+	mdByID := make(map[string]mangadexapi.Manga, len(followed))
+	for _, m := range followed {
+		mdByID[m.ID] = m
+	}
+
+	if id, ok := idx.MainTitleIndex[ex.Normalized]; ok {
+		ex.MainHits = append(ex.MainHits, id)
+	}
+	if id, ok := idx.AltTitleIndex[ex.Normalized]; ok {
+		ex.AltHits = append(ex.AltHits, id)
+	}
+
+	owners := buildOwnerSets(idx.IDToTitles)
+	ex.Owners = owners[ex.Normalized]
+	switch len(ex.Owners) {
+	case 1:
+		ex.Stage = "direct"
+		ex.ChosenID = ex.Owners[0]
+		ex.ChosenTitle = pickOriginalTitle(mdByID[ex.ChosenID])
+		ex.Reason = "normalized title is owned by exactly one followed manga"
+		return ex
+	case 0:
+		ex.reject("direct: normalized title is not in the follow indexes")
+	default:
+		ex.reject("direct: normalized title is ambiguous (%d followed manga)", len(ex.Owners))
+	}
+
+	ex.Threshold = distanceThreshold(len(ex.Normalized))
+	filtered := filterCandidates(idx.AllTitles, ex.Normalized, ex.Threshold)
+	ranks := fuzzy.RankFind(ex.Normalized, filtered)
+	ranked := make(map[string]int, len(ranks))
+	for _, r := range ranks {
+		ranked[r.Target] = r.Distance
+	}
+	for _, c := range filtered {
+		d, ok := ranked[c]
+		if !ok {
+			d = -1
+		}
+		ex.FuzzyCandidates = append(ex.FuzzyCandidates, FuzzyCandidate{Title: c, Distance: d, IDs: owners[c]})
+	}
+	sort.SliceStable(ex.FuzzyCandidates, func(a, b int) bool {
+		da, db := ex.FuzzyCandidates[a].Distance, ex.FuzzyCandidates[b].Distance
+		if (da < 0) != (db < 0) {
+			return db < 0
+		}
+		return da < db
+	})
+
+	// FuzzyMatch takes the first rank returned, so do the same here.
+	switch {
+	case len(filtered) == 0:
+		ex.reject("fuzzy: no followed title passed the length and first-letter filter")
+	case len(ranks) == 0:
+		ex.reject("fuzzy: no candidate contains the title's characters in order")
+	default:
+		best := FuzzyCandidate{Title: ranks[0].Target, Distance: ranks[0].Distance, IDs: owners[ranks[0].Target]}
+		switch {
+		case best.Distance > ex.Threshold:
+			ex.reject("fuzzy: best distance %d exceeds threshold %d", best.Distance, ex.Threshold)
+		case len(best.IDs) != 1:
+			ex.reject("fuzzy: %q is owned by %d followed manga", best.Title, len(best.IDs))
+		default:
+			ex.Stage = "fuzzy"
+			ex.ChosenID = best.IDs[0]
+			ex.ChosenTitle = pickOriginalTitle(mdByID[ex.ChosenID])
+			ex.Reason = fmt.Sprintf("closest followed title %q is within distance %d (threshold %d)", best.Title, best.Distance, ex.Threshold)
+			return ex
+		}
+	}
+
+	if client == nil {
+		return ex
+	}
+
+	entry := ImportEntry{Original: title, Normalized: ex.Normalized}
+	params := searchParams(entry, 10)
+	ex.Searched = true
+	ex.SearchQuery = params.ToValues().Encode()
+	mangas, err := client.GetMangaList(ctx, params)
+	if err != nil {
+		ex.SearchError = err
+		ex.reject("search: request failed: %v", err)
+		return ex
+	}
+	for _, m := range mangas {
+		ex.SearchResults = append(ex.SearchResults, SearchCandidate{
+			ID:         m.ID,
+			Title:      pickOriginalTitle(m),
+			ExactTitle: hasNormalizedTitle(m, ex.Normalized),
+		})
+	}
+	if len(mangas) == 0 {
+		ex.reject("search: no results")
+		return ex
+	}
+
+	info, id := matchSearchResults(entry, mangas)
+	if info == nil {
+		ex.reject("search: no result has a title within distance %d", ex.Threshold)
+		return ex
+	}
+	ex.Stage = "search"
+	ex.ChosenID = id
+	ex.ChosenTitle = info.MangaDexTitle
+	ex.Alternatives = info.Alternatives
+	if info.MatchType == "exact" {
+		ex.Reason = "search result title normalizes to the import title"
+	} else {
+		ex.Reason = fmt.Sprintf("closest search result title is within distance %d", ex.Threshold)
+	}
+	if len(info.Alternatives) > 0 {
+		ex.Reason += fmt.Sprintf("; preferred over %d entries sharing the title", len(info.Alternatives))
+	}
+	return ex
+}
+
+func (ex *Explanation) reject(format string, args ...any) {
+	ex.Rejections = append(ex.Rejections, fmt.Sprintf(format, args...))
+}
@@ -3,11 +3,15 @@ package match
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+	"github.com/Another0Noob/mangadex-import/internal/mangaparser"
 	"github.com/lithammer/fuzzysearch/fuzzy"
 )
 
@@ -16,13 +20,19 @@ import (
 type MatchInfo struct {
 	MangaDexTitle string
 	ImportTitle   string
-	MatchType     string // "exact" or "fuzzy"
+	ImportRows    []int         // source rows of the import entry
+	MatchType     string        // "exact" or "fuzzy"
+	Confidence    float64       // 1 for exact matches, lower the further a fuzzy match is
+	CoverURL      string        // empty unless cover_art was expanded
+	Alternatives  []Alternative // other entries sharing the matched title
+	Duplicates    []MatchInfo   // later import entries that resolved to the same manga
 }
 
 // ImportEntry bundles original manga with its normalized title
 type ImportEntry struct {
 	Original   string
 	Normalized string
+	Source     mangaparser.Entry
 }
 
 type FollowedIndexes struct {
@@ -38,6 +48,29 @@ type Unmatched struct {
 	MDIndexes FollowedIndexes
 }
 
+// Ambiguous returns the unmatched MangaDex manga that own the normalized
+// title of an unmatched import entry. MatchDirect and FuzzyMatch leave an
+// entry unmatched when several followed manga share its title.
+func (u Unmatched) Ambiguous() []mangadexapi.Manga {
+	titles := make(map[string]struct{}, len(u.Import))
+	for _, e := range u.Import {
+		if e.Normalized != "" {
+			titles[e.Normalized] = struct{}{}
+		}
+	}
+
+	var out []mangadexapi.Manga
+	for _, m := range u.MD {
+		for _, t := range u.MDIndexes.IDToTitles[m.ID] {
+			if _, ok := titles[t]; ok {
+				out = append(out, m)
+				break
+			}
+		}
+	}
+	return out
+}
+
 type MatchResult struct {
 	Matches   map[string]MatchInfo // key: MangaDex ID
 	Unmatched Unmatched
@@ -98,6 +131,11 @@ func pickOriginalTitle(m mangadexapi.Manga) string {
 	return ""
 }
 
+// DisplayTitle returns the human-friendly title used in logs and reports.
+func DisplayTitle(m mangadexapi.Manga) string {
+	return pickOriginalTitle(m)
+}
+
 // BuildFollowedIndexes creates searchable indexes from MangaDex manga
 func BuildFollowedIndexes(followed []mangadexapi.Manga) FollowedIndexes {
 	mainIdx := make(map[string]string, len(followed))
@@ -248,7 +286,7 @@ func rebuildIndexes(oldIdx FollowedIndexes, matchedIDs map[string]struct{}) Foll
 }
 
 // MatchDirect performs exact normalized title matching
-func MatchDirect(followed []mangadexapi.Manga, importManga []string) MatchResult {
+func MatchDirect(followed []mangadexapi.Manga, importManga []mangaparser.Entry) MatchResult {
 	if len(followed) == 0 || len(importManga) == 0 {
 		return MatchResult{
 			Matches: make(map[string]MatchInfo),
@@ -277,7 +315,7 @@ func MatchDirect(followed []mangadexapi.Manga, importManga []string) MatchResult
 
 	// Find exact matches (only when unambiguous)
 	for i, mm := range importManga {
-		n := NormalizeTitle(mm)
+		n := NormalizeTitle(mm.Title)
 		if n == "" {
 			continue
 		}
@@ -288,8 +326,11 @@ func MatchDirect(followed []mangadexapi.Manga, importManga []string) MatchResult
 			if _, seen := matches[id]; !seen {
 				matches[id] = MatchInfo{
 					MangaDexTitle: pickOriginalTitle(mdByID[id]),
-					ImportTitle:   mm,
+					ImportTitle:   mm.Title,
+					ImportRows:    mm.Rows,
 					MatchType:     "exact",
+					Confidence:    1,
+					CoverURL:      mdByID[id].CoverURL(),
 				}
 				matchedIDs[id] = struct{}{}
 				matchedImportIdx[i] = struct{}{}
@@ -309,10 +350,7 @@ func MatchDirect(followed []mangadexapi.Manga, importManga []string) MatchResult
 	unmatchedImport := make([]ImportEntry, 0, len(importManga)-len(matchedImportIdx))
 	for i, mm := range importManga {
 		if _, matched := matchedImportIdx[i]; !matched {
-			unmatchedImport = append(unmatchedImport, ImportEntry{
-				Original:   mm,
-				Normalized: NormalizeTitle(mm),
-			})
+			unmatchedImport = append(unmatchedImport, newImportEntry(mm))
 		}
 	}
 
@@ -326,18 +364,23 @@ func MatchDirect(followed []mangadexapi.Manga, importManga []string) MatchResult
 	}
 }
 
-// normalizeImportEntries converts MAL manga to MALEntry format
-func normalizeImportEntries(importManga []string) []ImportEntry {
+// normalizeImportEntries converts parsed import entries to ImportEntry format
+func normalizeImportEntries(importManga []mangaparser.Entry) []ImportEntry {
 	entries := make([]ImportEntry, len(importManga))
 	for i, mm := range importManga {
-		entries[i] = ImportEntry{
-			Original:   mm,
-			Normalized: NormalizeTitle(mm),
-		}
+		entries[i] = newImportEntry(mm)
 	}
 	return entries
 }
 
+func newImportEntry(e mangaparser.Entry) ImportEntry {
+	return ImportEntry{
+		Original:   e.Title,
+		Normalized: NormalizeTitle(e.Title),
+		Source:     e,
+	}
+}
+
 // FuzzyMatch adds fuzzy matches to existing MatchResult
 func FuzzyMatch(res MatchResult) MatchResult {
 	remaining := res.Unmatched.MDIndexes
@@ -397,7 +440,10 @@ func FuzzyMatch(res MatchResult) MatchResult {
 		newMatches[id] = MatchInfo{
 			MangaDexTitle: pickOriginalTitle(*md),
 			ImportTitle:   entry.Original,
+			ImportRows:    entry.Source.Rows,
 			MatchType:     "fuzzy",
+			Confidence:    fuzzyConfidence(ranks[0].Distance, len(pat)),
+			CoverURL:      md.CoverURL(),
 		}
 		matchedIDs[id] = struct{}{}
 		matchedImportIdx[i] = struct{}{}
@@ -444,6 +490,19 @@ func distanceThreshold(n int) int {
 	return th
 }
 
+// fuzzyConfidence maps an edit distance to a 0..1 score relative to the
+// pattern length.
+func fuzzyConfidence(distance, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	c := 1 - float64(distance)/float64(n)
+	if c < 0 {
+		return 0
+	}
+	return c
+}
+
 // filterCandidates pre-filters candidates by length and first rune
 func filterCandidates(allTitles []string, pattern string, threshold int) []string {
 	if len(allTitles) == 0 {
@@ -483,64 +542,158 @@ func abs(x int) int {
 	return x
 }
 
+var (
+	errNoTitle   = errors.New("No title")
+	errNoResults = errors.New("No search results")
+)
+
 func SearchAndMatch(ctx context.Context, client *mangadexapi.Client, importEntry ImportEntry, limit int) (*MatchInfo, string, error) {
 	if importEntry.Normalized == "" {
-		return nil, "", errors.New("No title")
+		return nil, "", errNoTitle
 	}
 
-	params := mangadexapi.QueryParams{
-		Title: importEntry.Normalized,
-		Limit: limit,
-		Order: mangadexapi.OrderParams{"relevance": "desc"},
-	}
-	mangas, err := client.GetMangaList(ctx, params)
+	mangas, err := client.GetMangaList(ctx, searchParams(importEntry, limit))
 	if err != nil {
 		return nil, "", err
 	}
 
 	if len(mangas) == 0 {
-		return nil, "", errors.New("No search results")
+		return nil, "", errNoResults
+	}
+
+	info, id := matchSearchResults(importEntry, mangas)
+	return info, id, nil
+}
+
+// searchParams builds the /manga query used to look up an import entry.
+func searchParams(importEntry ImportEntry, limit int) mangadexapi.QueryParams {
+	return mangadexapi.QueryParams{
+		Title:    importEntry.Normalized,
+		Limit:    limit,
+		Order:    mangadexapi.OrderParams{"relevance": "desc"},
+		Includes: []mangadexapi.ReferenceExpansionManga{mangadexapi.RefExpCoverArt},
 	}
+}
 
-	// Exact match
+// matchSearchResults picks the search result matching importEntry, if any.
+func matchSearchResults(importEntry ImportEntry, mangas []mangadexapi.Manga) (*MatchInfo, string) {
+	// Exact match; several results may share the title (colored editions,
+	// doujinshi, oneshots), so collect them all and keep the canonical one.
+	var exact []mangadexapi.Manga
 	for _, manga := range mangas {
-		for lang, title := range manga.Attributes.Title {
-			if isEnglishOrRomanized(lang) && NormalizeTitle(title) == importEntry.Normalized {
-				return &MatchInfo{
-					MangaDexTitle: pickOriginalTitle(manga),
-					ImportTitle:   importEntry.Original,
-					MatchType:     "exact",
-				}, manga.ID, nil
-			}
-		}
-		for _, altTitle := range manga.Attributes.AltTitles {
-			for lang, title := range altTitle {
-				if isEnglishOrRomanized(lang) && NormalizeTitle(title) == importEntry.Normalized {
-					return &MatchInfo{
-						MangaDexTitle: pickOriginalTitle(manga),
-						ImportTitle:   importEntry.Original,
-						MatchType:     "exact",
-					}, manga.ID, nil
-				}
-			}
+		if hasNormalizedTitle(manga, importEntry.Normalized) {
+			exact = append(exact, manga)
 		}
 	}
+	if len(exact) > 0 {
+		best, alts := pickCanonical(exact)
+		logAlternatives(importEntry.Original, exact[best], alts)
+		return &MatchInfo{
+			MangaDexTitle: pickOriginalTitle(exact[best]),
+			ImportTitle:   importEntry.Original,
+			ImportRows:    importEntry.Source.Rows,
+			MatchType:     "exact",
+			Confidence:    1,
+			CoverURL:      exact[best].CoverURL(),
+			Alternatives:  alts,
+		}, exact[best].ID
+	}
 
-	res, err := fuzzyMatchSingle(importEntry.Normalized, mangas)
+	res, dist, alts, err := fuzzyMatchSingle(importEntry.Normalized, mangas)
 	if err == nil && res != nil {
+		logAlternatives(importEntry.Original, *res, alts)
 		return &MatchInfo{
 			MangaDexTitle: pickOriginalTitle(*res),
 			ImportTitle:   importEntry.Original,
+			ImportRows:    importEntry.Source.Rows,
 			MatchType:     "fuzzy",
-		}, res.ID, nil
+			Confidence:    fuzzyConfidence(dist, len(importEntry.Normalized)),
+			CoverURL:      res.CoverURL(),
+			Alternatives:  alts,
+		}, res.ID
 	}
 
-	return nil, "", nil
+	return nil, ""
 }
 
-func fuzzyMatchSingle(input string, mdList []mangadexapi.Manga) (*mangadexapi.Manga, error) {
+// hasNormalizedTitle reports whether any English or romanized main or alt
+// title of m normalizes to norm.
+func hasNormalizedTitle(m mangadexapi.Manga, norm string) bool {
+	for lang, title := range m.Attributes.Title {
+		if isEnglishOrRomanized(lang) && NormalizeTitle(title) == norm {
+			return true
+		}
+	}
+	for _, altTitle := range m.Attributes.AltTitles {
+		for lang, title := range altTitle {
+			if isEnglishOrRomanized(lang) && NormalizeTitle(title) == norm {
+				return true
+			}
+		}
+	}
+	return false
+}
 
-	// Build: candidates = []string, owner = map[normalizedTitle][]index
+func logAlternatives(importTitle string, chosen mangadexapi.Manga, alts []Alternative) {
+	if len(alts) == 0 {
+		return
+	}
+	perID := make([]string, 0, len(alts))
+	for _, a := range alts {
+		perID = append(perID, a.ID+" ("+a.Reason+")")
+	}
+	log.Printf("%q matched several MangaDex entries; chose %s, alternatives: %v", importTitle, chosen.ID, perID)
+}
+
+func fuzzyMatchSingle(input string, mdList []mangadexapi.Manga) (*mangadexapi.Manga, int, []Alternative, error) {
+	candidates, owners := titleCandidates(mdList)
+
+	thr := distanceThreshold(len(input))
+
+	candidates = filterCandidates(candidates, input, thr)
+	if len(candidates) == 0 {
+		return nil, 0, nil, nil
+	}
+
+	ranks := fuzzy.RankFind(input, candidates)
+	if len(ranks) == 0 {
+		return nil, 0, nil, nil
+	}
+
+	best := ranks[0]
+	if best.Distance > thr {
+		return nil, 0, nil, nil
+	}
+
+	idxList := owners[best.Target]
+
+	// If the same manga index was appended multiple times (duplicate main/alt same norm),
+	// deduplicate indexes to avoid treating them as multiple owners.
+	if len(idxList) > 1 {
+		seen := make(map[int]struct{})
+		uniq := make([]int, 0, len(idxList))
+		for _, idx := range idxList {
+			if _, ok := seen[idx]; ok {
+				continue
+			}
+			seen[idx] = struct{}{}
+			uniq = append(uniq, idx)
+		}
+		idxList = uniq
+	}
+
+	group := make([]mangadexapi.Manga, len(idxList))
+	for i, idx := range idxList {
+		group[i] = mdList[idx]
+	}
+	pick, alts := pickCanonical(group)
+
+	return &mdList[idxList[pick]], best.Distance, alts, nil
+}
+
+// titleCandidates collects the normalized English/romanized titles of mdList
+// along with the indexes of the manga owning each title.
+func titleCandidates(mdList []mangadexapi.Manga) ([]string, map[string][]int) {
 	candidates := []string{}
 	owners := make(map[string][]int) // normalized title -> manga indexes
 
@@ -573,69 +726,314 @@ func fuzzyMatchSingle(input string, mdList []mangadexapi.Manga) (*mangadexapi.Ma
 			}
 		}
 	}
+	return candidates, owners
+}
 
-	thr := distanceThreshold(len(input))
+// SearchState is the outcome of searching for one import entry.
+type SearchState struct {
+	MangaID  string     `json:"manga_id,omitempty"` // empty if the search found nothing
+	Match    *MatchInfo `json:"match,omitempty"`
+	Followed bool       `json:"followed,omitempty"`
+}
 
-	candidates = filterCandidates(candidates, input, thr)
-	if len(candidates) == 0 {
-		return nil, nil
+// Checkpoint persists search outcomes so an interrupted run can skip the
+// entries it already handled.
+type Checkpoint interface {
+	Lookup(e ImportEntry) (SearchState, bool)
+	Save(e ImportEntry, s SearchState) error
+}
+
+// SearchOptions configures SearchAndFollow.
+type SearchOptions struct {
+	Follow     bool                // follow each match
+	Followed   map[string]struct{} // IDs the user already follows, never followed again
+	Checkpoint Checkpoint          // optional, resumes from and records search outcomes
+	Workers    int                 // entries handled concurrently, at least 1
+}
+
+// SearchResult is the outcome of SearchAndFollow. Matches are keyed by
+// MangaDex ID; further import entries resolving to the same manga are kept
+// in the first match's Duplicates.
+type SearchResult struct {
+	New             map[string]MatchInfo // matches not followed before this run
+	AlreadyFollowed map[string]MatchInfo // matches already in the user's follows
+	FollowRejected  map[string]MatchInfo // matches whose follow the API rejected
+	Unmatched       []ImportEntry
+}
+
+// Per-entry outcomes of the search stage.
+const (
+	outcomeUnmatched = iota
+	outcomeNew
+	outcomeAlreadyFollowed
+	outcomeFollowRejected
+)
+
+type searchOutcome struct {
+	kind  int
+	state SearchState
+}
+
+// SearchAndFollow searches MangaDex for each import entry and, when
+// opts.Follow is set, follows matches not in opts.Followed. Up to
+// opts.Workers entries are handled concurrently; all requests still share
+// the client's rate limit, and the result does not depend on their timing.
+func SearchAndFollow(ctx context.Context, client *mangadexapi.Client, importEntries []ImportEntry, opts SearchOptions) (*SearchResult, error) {
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
 	}
 
-	ranks := fuzzy.RankFind(input, candidates)
-	if len(ranks) == 0 {
-		return nil, nil
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s := &searcher{
+		client:   client,
+		opts:     opts,
+		claimed:  make(map[string]struct{}),
+		rejected: make(map[string]struct{}),
+	}
+	outcomes := make([]searchOutcome, len(importEntries))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				out, err := s.handle(ctx, importEntries[i])
+				if err != nil {
+					s.fail(err)
+					cancel()
+					continue
+				}
+				outcomes[i] = out
+			}
+		}()
 	}
 
-	best := ranks[0]
-	if best.Distance > thr {
-		return nil, nil
+feed:
+	for i := range importEntries {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
 	}
+	close(jobs)
+	wg.Wait()
 
-	idxList := owners[best.Target]
+	if s.err != nil {
+		return nil, s.err
+	}
+	if err := parent.Err(); err != nil {
+		return nil, err
+	}
 
-	// If the same manga index was appended multiple times (duplicate main/alt same norm),
-	// deduplicate indexes to avoid treating them as multiple owners.
-	if len(idxList) > 1 {
-		seen := make(map[int]struct{})
-		uniq := make([]int, 0, len(idxList))
-		for _, idx := range idxList {
-			if _, ok := seen[idx]; ok {
-				continue
-			}
-			seen[idx] = struct{}{}
-			uniq = append(uniq, idx)
+	// Assemble in input order so duplicates resolve the same way every run.
+	res := &SearchResult{
+		New:             make(map[string]MatchInfo),
+		AlreadyFollowed: make(map[string]MatchInfo),
+		FollowRejected:  make(map[string]MatchInfo),
+		Unmatched:       []ImportEntry{},
+	}
+	for i, out := range outcomes {
+		// Entries that waited on a claim share the claimant's fate.
+		if _, ok := s.rejected[out.state.MangaID]; ok && out.kind == outcomeNew {
+			out.kind = outcomeFollowRejected
 		}
-		idxList = uniq
+		switch out.kind {
+		case outcomeNew:
+			addMatch(res.New, out.state.MangaID, *out.state.Match)
+		case outcomeFollowRejected:
+			addMatch(res.FollowRejected, out.state.MangaID, *out.state.Match)
+		case outcomeAlreadyFollowed:
+			addMatch(res.AlreadyFollowed, out.state.MangaID, *out.state.Match)
+		default:
+			res.Unmatched = append(res.Unmatched, importEntries[i])
+		}
+	}
+	return res, nil
+}
+
+// addMatch records mi under id, as a duplicate if id already has a match.
+func addMatch(matches map[string]MatchInfo, id string, mi MatchInfo) {
+	first, ok := matches[id]
+	if !ok {
+		matches[id] = mi
+		return
 	}
+	first.Duplicates = append(first.Duplicates, mi)
+	matches[id] = first
+}
+
+// searcher holds the state shared by SearchAndFollow's workers.
+type searcher struct {
+	client *mangadexapi.Client
+	opts   SearchOptions
 
-	idx := idxList[0]
+	mu       sync.Mutex
+	claimed  map[string]struct{} // IDs followed (or being followed) by this run
+	rejected map[string]struct{} // claimed IDs whose follow was rejected
+	err      error
+}
 
-	return &mdList[idx], nil
+func (s *searcher) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
 }
 
-func SearchAndFollow(ctx context.Context, client *mangadexapi.Client, importEntries []ImportEntry, follow bool) ([]string, []ImportEntry, error) {
-	var newMatches []string
-	stillUnmatched := []ImportEntry{}
-	for _, importEntry := range importEntries {
+// handle searches for one entry, or takes its outcome from the checkpoint,
+// and follows the match if no other entry has claimed it.
+func (s *searcher) handle(ctx context.Context, importEntry ImportEntry) (searchOutcome, error) {
+	state, done := SearchState{}, false
+	if s.opts.Checkpoint != nil {
+		state, done = s.opts.Checkpoint.Lookup(importEntry)
+	}
 
-		matchInfo, id, err := SearchAndMatch(ctx, client, importEntry, 10)
+	if !done {
+		var (
+			matchInfo *MatchInfo
+			id        string
+		)
+		err := s.call(ctx, func() error {
+			var err error
+			matchInfo, id, err = SearchAndMatch(ctx, s.client, importEntry, 10)
+			return err
+		})
+		if err != nil && ctx.Err() != nil {
+			return searchOutcome{}, ctx.Err()
+		}
+		if errors.Is(err, mangadexapi.ErrAuth) {
+			return searchOutcome{}, err
+		}
+		if mangadexapi.IsBadRequest(err) {
+			// The API rejects this title; searching again won't help.
+			log.Printf("Search rejected for %q: %v", importEntry.Original, err)
+			err = errNoResults
+		}
+		if err != nil && !errors.Is(err, errNoTitle) && !errors.Is(err, errNoResults) {
+			// Transient failure, search again on resume.
+			log.Printf("Search failed for %q, leaving it unmatched: %v", importEntry.Original, err)
+			return searchOutcome{kind: outcomeUnmatched}, nil
+		}
+		state = SearchState{MangaID: id, Match: matchInfo}
+		if err := saveState(s.opts.Checkpoint, importEntry, state); err != nil {
+			return searchOutcome{}, err
+		}
+	}
+
+	if state.Match == nil {
+		return searchOutcome{kind: outcomeUnmatched}, nil
+	}
+
+	// A match followed by an earlier, interrupted run is in opts.Followed
+	// by now but still counts as new.
+	if state.Followed {
+		s.claim(state.MangaID)
+		return searchOutcome{kind: outcomeNew, state: state}, nil
+	}
+
+	s.mu.Lock()
+	_, claimed := s.claimed[state.MangaID]
+	_, followed := s.opts.Followed[state.MangaID]
+	if !claimed && !followed {
+		s.claimed[state.MangaID] = struct{}{}
+	}
+	s.mu.Unlock()
+
+	switch {
+	case claimed:
+		return searchOutcome{kind: outcomeNew, state: state}, nil
+	case followed:
+		return searchOutcome{kind: outcomeAlreadyFollowed, state: state}, nil
+	}
+
+	if s.opts.Follow {
+		err := s.call(ctx, func() error {
+			return s.client.FollowManga(ctx, state.MangaID)
+		})
+		if mangadexapi.IsBadRequest(err) {
+			log.Printf("Skipping %q, follow of %s rejected: %v", importEntry.Original, state.MangaID, err)
+			s.mu.Lock()
+			s.rejected[state.MangaID] = struct{}{}
+			s.mu.Unlock()
+			return searchOutcome{kind: outcomeFollowRejected, state: state}, nil
+		}
 		if err != nil {
-			stillUnmatched = append(stillUnmatched, importEntry)
-			continue
+			return searchOutcome{}, err
+		}
+		state.Followed = true
+		if err := saveState(s.opts.Checkpoint, importEntry, state); err != nil {
+			return searchOutcome{}, err
 		}
+	}
+	return searchOutcome{kind: outcomeNew, state: state}, nil
+}
 
-		if matchInfo != nil {
-			newMatches = append(newMatches, importEntry.Original)
-			if follow {
-				if err := client.FollowManga(ctx, id); err != nil {
-					return nil, nil, err
-				}
+// call runs fn and reacts to API errors the client's retries could not
+// resolve: a 429 pauses once for the requested time before trying again.
+// Expired tokens are renewed by the client itself.
+func (s *searcher) call(ctx context.Context, fn func() error) error {
+	paused := false
+	for {
+		err := fn()
+		switch {
+		case mangadexapi.IsRateLimited(err) && !paused:
+			paused = true
+			if err := pause(ctx, err); err != nil {
+				return err
 			}
-
-		} else {
-			stillUnmatched = append(stillUnmatched, importEntry)
+		default:
+			return err
 		}
 	}
+}
 
-	return newMatches, stillUnmatched, nil
+// pause waits for the Retry-After of a rate limited error, or a minute.
+func pause(ctx context.Context, err error) error {
+	d := time.Minute
+	var apiErr *mangadexapi.Error
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		d = apiErr.RetryAfter
+	}
+	log.Printf("Rate limited, pausing for %s", d.Round(time.Second))
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *searcher) claim(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claimed[id] = struct{}{}
+}
+
+// FollowedIDs returns the set of IDs in followed, for SearchOptions.Followed.
+func FollowedIDs(followed []mangadexapi.Manga) map[string]struct{} {
+	ids := make(map[string]struct{}, len(followed))
+	for _, m := range followed {
+		ids[m.ID] = struct{}{}
+	}
+	return ids
+}
+
+func saveState(cp Checkpoint, e ImportEntry, s SearchState) error {
+	if cp == nil {
+		return nil
+	}
+	if err := cp.Save(e, s); err != nil {
+		return fmt.Errorf("save checkpoint: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,53 @@
+package match
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi/mdfake"
+	"github.com/Another0Noob/mangadex-import/internal/mangaparser"
+)
+
+type memCheckpoint map[string]SearchState
+
+func (m memCheckpoint) Lookup(e ImportEntry) (SearchState, bool) {
+	s, ok := m[e.Normalized]
+	return s, ok
+}
+
+func (m memCheckpoint) Save(e ImportEntry, s SearchState) error {
+	m[e.Normalized] = s
+	return nil
+}
+
+func TestSearchAndFollowAbortsOnAuthFailure(t *testing.T) {
+	srv := mdfake.New()
+	t.Cleanup(srv.Close)
+	srv.AddManga(mdfake.NewManga("bs", "Berserk"))
+	ctx := context.Background()
+
+	client := mangadexapi.NewClient(srv.Options()...)
+	if err := client.LoadAuthForm(srv.AuthForm()); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Authenticate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The refresh and the fallback login are both rejected with a 400.
+	srv.RevokeTokens()
+	srv.Fail(http.MethodPost, "/auth/token", http.StatusBadRequest, 2)
+
+	cp := memCheckpoint{}
+	entries := normalizeImportEntries([]mangaparser.Entry{{Title: "Berserk"}})
+	_, err := SearchAndFollow(ctx, client, entries, SearchOptions{Follow: true, Checkpoint: cp})
+	if !errors.Is(err, mangadexapi.ErrAuth) || mangadexapi.IsBadRequest(err) {
+		t.Errorf("err = %v, want ErrAuth", err)
+	}
+	if len(cp) != 0 {
+		t.Errorf("checkpointed %v after an auth failure", cp)
+	}
+}
@@ -2,6 +2,7 @@ package match
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
 	"unicode"
 
@@ -30,37 +31,66 @@ func stripDiacritics(s string) string {
 }
 
 func NormalizeTitle(s string) string {
-	s = strings.TrimSpace(s)
+	return normalizeTitle(s, nil)
+}
+
+// NormalizeStep records a normalization rule that changed the title.
+type NormalizeStep struct {
+	Rule   string
+	Result string
+}
+
+// NormalizeTitleTrace normalizes s like NormalizeTitle and also returns the
+// rules that changed it, in the order they were applied.
+func NormalizeTitleTrace(s string) (string, []NormalizeStep) {
+	var steps []NormalizeStep
+	out := normalizeTitle(s, func(rule, result string) {
+		steps = append(steps, NormalizeStep{Rule: rule, Result: result})
+	})
+	return out, steps
+}
+
+// normalizeTitle implements NormalizeTitle; trace, when non-nil, is called
+// after every rule that modified the string.
+func normalizeTitle(s string, trace func(rule, result string)) string {
+	apply := func(rule, next string) {
+		if trace != nil && next != s {
+			trace(rule, next)
+		}
+		s = next
+	}
+
+	apply("trim spaces", strings.TrimSpace(s))
 	if s == "" {
 		return ""
 	}
 
 	// Unicode normalization (NFKC) to fold width/compatibility forms (full‑width, etc.)
-	s = norm.NFKC.String(s)
+	apply("unicode NFKC", norm.NFKC.String(s))
 
 	// Remove diacritics (é -> e, ñ -> n, ō -> o)
-	s = stripDiacritics(s)
+	apply("strip diacritics", stripDiacritics(s))
 
-	s = strings.ToLower(s)
+	apply("lowercase", strings.ToLower(s))
 
 	// Remove special suffixes before stripping punctuation
 	for _, r := range []string{
 		"@comic",
 		"the comic",
 	} {
-		s = strings.ReplaceAll(s, r, "")
+		apply("remove "+strconv.Quote(r), strings.ReplaceAll(s, r, ""))
 	}
 
-	s = trailingParen.ReplaceAllString(s, "")
+	apply("remove trailing parenthesis", trailingParen.ReplaceAllString(s, ""))
 
-	s = reNonAlnum.ReplaceAllString(s, "")
+	apply("remove punctuation", reNonAlnum.ReplaceAllString(s, ""))
 
-	s = reMinus.ReplaceAllString(s, " ")
+	apply("hyphens to spaces", reMinus.ReplaceAllString(s, " "))
 
 	// Normalize 'wo' particle to 'o'
 	padded := " " + s + " "
 	padded = strings.ReplaceAll(padded, " wo ", " o ")
-	s = strings.TrimSpace(padded)
+	apply("particle wo to o", strings.TrimSpace(padded))
 
 	// Token handling
 	tokens := strings.Fields(s)
@@ -82,7 +112,7 @@ func NormalizeTitle(s string) string {
 		out = append(out, tok)
 	}
 
-	s = strings.Join(out, " ")
-	s = reMultiSpace.ReplaceAllString(s, " ")
-	return strings.TrimSpace(s)
+	apply("tokens node/no de to no", strings.Join(out, " "))
+	apply("collapse spaces", strings.TrimSpace(reMultiSpace.ReplaceAllString(s, " ")))
+	return s
 }
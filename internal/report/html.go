@@ -0,0 +1,49 @@
+package report
+
+import (
+	"html/template"
+	"io"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(f float64) int { return int(f*100 + 0.5) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>MangaDex import report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 6px; text-align: left; vertical-align: top; }
+img { width: 64px; }
+.unmatched { color: #b00; }
+.alt { font-size: 0.85em; color: #555; }
+</style>
+</head>
+<body>
+<h1>MangaDex import report</h1>
+<p>{{.Source}} &middot; {{.GeneratedAt.Format "2006-01-02 15:04"}}</p>
+<ul>
+{{range $outcome, $n := .Counts}}<li>{{$outcome}}: {{$n}}</li>
+{{end}}</ul>
+<table>
+<tr><th></th><th>Import title</th><th>Outcome</th><th>Match</th><th>MangaDex</th></tr>
+{{range .Rows}}<tr{{if eq .Outcome "unmatched"}} class="unmatched"{{end}}>
+<td>{{if .CoverURL}}<img src="{{.CoverURL}}" alt="" loading="lazy">{{end}}</td>
+<td>{{.ImportTitle}}</td>
+<td>{{.Outcome}}</td>
+<td>{{if .MatchType}}{{.MatchType}} ({{percent .Confidence}}%){{end}}</td>
+<td>{{if .URL}}<a href="{{.URL}}">{{.MangaDexTitle}}</a>{{end}}
+{{range .Alternatives}}<div class="alt">alternative: <a href="https://mangadex.org/title/{{.ID}}">{{.Title}}</a> ({{.Reason}})</div>
+{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes a self-contained HTML page with links and cover thumbnails.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, r)
+}
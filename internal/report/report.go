@@ -0,0 +1,216 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Another0Noob/mangadex-import/internal/match"
+)
+
+// Outcomes for a report row.
+const (
+	OutcomeAlreadyFollowed = "already followed"
+	// OutcomeAlreadyFollowedSearch is a followed title that only the search
+	// stage matched, e.g. because the import title differs from MangaDex's.
+	OutcomeAlreadyFollowedSearch = "already followed (matched via search)"
+	OutcomeFollowed              = "followed"
+	// OutcomeFollowRejected is a search match the API refused to follow.
+	OutcomeFollowRejected = "follow rejected"
+	OutcomeFound          = "found"
+	OutcomeUnmatched      = "unmatched"
+)
+
+// Row is the outcome for a single import entry.
+type Row struct {
+	ImportTitle   string              `json:"import_title"`
+	ImportRows    []int               `json:"import_rows,omitempty"`
+	Outcome       string              `json:"outcome"`
+	MatchType     string              `json:"match_type,omitempty"` // "direct", "fuzzy", "search exact", "search fuzzy"
+	Confidence    float64             `json:"confidence,omitempty"`
+	MangaDexID    string              `json:"mangadex_id,omitempty"`
+	MangaDexTitle string              `json:"mangadex_title,omitempty"`
+	URL           string              `json:"url,omitempty"`
+	CoverURL      string              `json:"cover_url,omitempty"`
+	Alternatives  []match.Alternative `json:"alternatives,omitempty"`
+}
+
+// Report lists every import entry of a match or follow run.
+type Report struct {
+	Source      string    `json:"source"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Rows        []Row     `json:"rows"`
+}
+
+// New creates an empty report for the given input file.
+func New(source string) *Report {
+	return &Report{Source: source, GeneratedAt: time.Now()}
+}
+
+// AddMatches adds a row per matched import entry, including duplicates.
+// stage prefixes the match type, e.g. "search" turns "exact" into "search
+// exact"; an empty stage uses "direct" for exact matches.
+func (r *Report) AddMatches(matches map[string]match.MatchInfo, stage, outcome string) {
+	for id, first := range matches {
+		for _, mi := range append([]match.MatchInfo{first}, first.Duplicates...) {
+			r.addMatch(id, mi, stage, outcome)
+		}
+	}
+}
+
+func (r *Report) addMatch(id string, mi match.MatchInfo, stage, outcome string) {
+	matchType := mi.MatchType
+	switch {
+	case stage != "":
+		matchType = stage + " " + matchType
+	case matchType == "exact":
+		matchType = "direct"
+	}
+	r.Rows = append(r.Rows, Row{
+		ImportTitle:   mi.ImportTitle,
+		ImportRows:    mi.ImportRows,
+		Outcome:       outcome,
+		MatchType:     matchType,
+		Confidence:    mi.Confidence,
+		MangaDexID:    id,
+		MangaDexTitle: mi.MangaDexTitle,
+		URL:           "https://mangadex.org/title/" + id,
+		CoverURL:      mi.CoverURL,
+		Alternatives:  mi.Alternatives,
+	})
+}
+
+// AddUnmatched adds a row per entry that found no MangaDex match.
+func (r *Report) AddUnmatched(entries []match.ImportEntry) {
+	for _, e := range entries {
+		r.Rows = append(r.Rows, Row{
+			ImportTitle: e.Original,
+			ImportRows:  e.Source.Rows,
+			Outcome:     OutcomeUnmatched,
+		})
+	}
+}
+
+// Sort orders rows by their first source row, then title.
+func (r *Report) Sort() {
+	first := func(row Row) int {
+		if len(row.ImportRows) == 0 {
+			return int(^uint(0) >> 1)
+		}
+		return row.ImportRows[0]
+	}
+	sort.SliceStable(r.Rows, func(a, b int) bool {
+		fa, fb := first(r.Rows[a]), first(r.Rows[b])
+		if fa != fb {
+			return fa < fb
+		}
+		return r.Rows[a].ImportTitle < r.Rows[b].ImportTitle
+	})
+}
+
+// Counts returns the number of rows per outcome.
+func (r *Report) Counts() map[string]int {
+	counts := make(map[string]int)
+	for _, row := range r.Rows {
+		counts[row.Outcome]++
+	}
+	return counts
+}
+
+// Write writes the report in the given format ("csv", "json" or "html").
+func (r *Report) Write(w io.Writer, format string) error {
+	if err := checkFormat(format); err != nil {
+		return err
+	}
+	switch format {
+	case "csv":
+		return r.WriteCSV(w)
+	case "json":
+		return r.WriteJSON(w)
+	default:
+		return r.WriteHTML(w)
+	}
+}
+
+func checkFormat(format string) error {
+	switch format {
+	case "csv", "json", "html":
+		return nil
+	}
+	return fmt.Errorf("unknown report format: %s (must be csv, json or html)", format)
+}
+
+// WriteFile writes the report to path, picking the format from its extension.
+// An unknown extension fails before the file is created.
+func (r *Report) WriteFile(path string) error {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if format == "htm" {
+		format = "html"
+	}
+	if err := checkFormat(format); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create report: %w", err)
+	}
+	defer file.Close()
+
+	if err := r.Write(file, format); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// WriteCSV writes one line per row; alternatives are joined as "id (reason)".
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{"import_title", "import_rows", "outcome", "match_type", "confidence", "mangadex_id", "mangadex_title", "url", "alternatives"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range r.Rows {
+		rows := make([]string, len(row.ImportRows))
+		for i, n := range row.ImportRows {
+			rows[i] = strconv.Itoa(n)
+		}
+		alts := make([]string, len(row.Alternatives))
+		for i, a := range row.Alternatives {
+			alts[i] = a.ID + " (" + a.Reason + ")"
+		}
+		confidence := ""
+		if row.Outcome != OutcomeUnmatched {
+			confidence = strconv.FormatFloat(row.Confidence, 'f', 2, 64)
+		}
+		if err := cw.Write([]string{
+			row.ImportTitle,
+			strings.Join(rows, " "),
+			row.Outcome,
+			row.MatchType,
+			confidence,
+			row.MangaDexID,
+			row.MangaDexTitle,
+			row.URL,
+			strings.Join(alts, "; "),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the whole report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,20 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileUnknownFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.txt")
+	if err := os.WriteFile(path, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := New("list.xml").WriteFile(path); err == nil {
+		t.Fatal("wrote a report with an unknown extension")
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "keep" {
+		t.Errorf("existing file changed to %q, %v", data, err)
+	}
+}
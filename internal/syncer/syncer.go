@@ -0,0 +1,341 @@
+package syncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+	"github.com/Another0Noob/mangadex-import/internal/mangaparser"
+	"github.com/Another0Noob/mangadex-import/internal/match"
+)
+
+// Policy decides which side wins a status conflict.
+type Policy string
+
+const (
+	PolicySource   Policy = "source"   // the source list always wins
+	PolicyMangaDex Policy = "mangadex" // MangaDex always wins
+	PolicyNewest   Policy = "newest"   // the side changed since the last sync wins
+)
+
+// ParsePolicy validates a --policy value.
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case PolicySource, PolicyMangaDex, PolicyNewest:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown policy: %s (must be source, mangadex or newest)", s)
+	}
+}
+
+// Winners of a conflict.
+const (
+	WinnerSource   = "source"
+	WinnerMangaDex = "mangadex"
+	WinnerNone     = "" // unresolved, left as is
+)
+
+// Conflict is a matched title whose status differs between both sides.
+type Conflict struct {
+	MangaID        string
+	Title          string
+	Entry          mangaparser.Entry
+	SourceStatus   mangadexapi.ReadingStatus
+	MangaDexStatus mangadexapi.ReadingStatus
+	Winner         string
+	Reason         string
+}
+
+// Diff is the three-way comparison between a source list and MangaDex.
+type Diff struct {
+	Matched        map[string]mangaparser.Entry // MangaDex ID -> source entry
+	OnlyInSource   []match.ImportEntry
+	OnlyInMangaDex []mangadexapi.Manga
+	Conflicts      []Conflict
+
+	statuses map[string]mangadexapi.ReadingStatus
+	followed map[string]struct{}
+}
+
+// Compare matches the source entries against the user's follows with
+// match.MatchDirect and match.FuzzyMatch and classifies every title.
+func Compare(followed []mangadexapi.Manga, statuses map[string]mangadexapi.ReadingStatus, entries []mangaparser.Entry, policy Policy, state *State) *Diff {
+	res := match.FuzzyMatch(match.MatchDirect(followed, entries))
+
+	byRow := make(map[int]mangaparser.Entry, len(entries))
+	for _, e := range entries {
+		if len(e.Rows) > 0 {
+			byRow[e.Rows[0]] = e
+		}
+	}
+
+	d := &Diff{
+		Matched:        make(map[string]mangaparser.Entry, len(res.Matches)),
+		OnlyInSource:   res.Unmatched.Import,
+		OnlyInMangaDex: res.Unmatched.MD,
+		statuses:       statuses,
+		followed:       match.FollowedIDs(followed),
+	}
+
+	ids := make([]string, 0, len(res.Matches))
+	for id := range res.Matches {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		mi := res.Matches[id]
+		if len(mi.ImportRows) == 0 {
+			continue
+		}
+		e := byRow[mi.ImportRows[0]]
+		d.Matched[id] = e
+
+		src := mangadexapi.ReadingStatus(e.Status)
+		md := statuses[id]
+		if src == "" || src == md {
+			continue
+		}
+		c := Conflict{
+			MangaID:        id,
+			Title:          mi.MangaDexTitle,
+			Entry:          e,
+			SourceStatus:   src,
+			MangaDexStatus: md,
+		}
+		c.Winner, c.Reason = resolve(policy, c, state)
+		d.Conflicts = append(d.Conflicts, c)
+	}
+	return d
+}
+
+func resolve(policy Policy, c Conflict, state *State) (string, string) {
+	switch policy {
+	case PolicySource:
+		return WinnerSource, "source wins"
+	case PolicyMangaDex:
+		return WinnerMangaDex, "MangaDex wins"
+	}
+
+	// Newest wins. MangaDex has no status timestamps, so a MangaDex change
+	// is detected by comparing with the status recorded at the last sync.
+	if c.MangaDexStatus == "" {
+		return WinnerSource, "no MangaDex status"
+	}
+	recorded, known := state.Statuses[c.MangaID]
+	mdChanged := known && recorded != c.MangaDexStatus
+	srcChanged := !c.Entry.UpdatedAt.IsZero() && c.Entry.UpdatedAt.After(state.LastSync)
+
+	switch {
+	case srcChanged && !mdChanged:
+		return WinnerSource, "source updated " + c.Entry.UpdatedAt.Format("2006-01-02")
+	case mdChanged && !srcChanged:
+		return WinnerMangaDex, "MangaDex changed since last sync"
+	case mdChanged && srcChanged:
+		return WinnerNone, "changed on both sides since last sync"
+	default:
+		return WinnerNone, "no update date to compare"
+	}
+}
+
+// Apply writes the statuses of conflicts won by the source to MangaDex.
+// progress, if non-nil, is called after each write.
+func (d *Diff) Apply(ctx context.Context, client *mangadexapi.Client, progress func(Conflict, error)) (int, error) {
+	applied := 0
+	for _, c := range d.Conflicts {
+		if c.Winner != WinnerSource {
+			continue
+		}
+		err := client.UpdateMangaStatus(ctx, c.MangaID, c.SourceStatus)
+		if progress != nil {
+			progress(c, err)
+		}
+		if err != nil {
+			return applied, fmt.Errorf("update status %s: %w", c.MangaID, err)
+		}
+		d.statuses[c.MangaID] = c.SourceStatus
+		applied++
+	}
+	return applied, nil
+}
+
+// FollowMissing searches MangaDex for titles only in the source list, follows
+// the matches and applies their source status. Matches that turn out to be
+// followed already are moved out of OnlyInMangaDex without a status change.
+// It returns the number of new follows; titles still unmatched, or that
+// MangaDex refused to follow, stay in OnlyInSource.
+func (d *Diff) FollowMissing(ctx context.Context, client *mangadexapi.Client) (int, error) {
+	res, err := match.SearchAndFollow(ctx, client, d.OnlyInSource, match.SearchOptions{
+		Follow:   true,
+		Followed: d.followed,
+		Workers:  4,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	byRow := make(map[int]mangaparser.Entry, len(d.OnlyInSource))
+	for _, ie := range d.OnlyInSource {
+		if len(ie.Source.Rows) > 0 {
+			byRow[ie.Source.Rows[0]] = ie.Source
+		}
+	}
+
+	for id, mi := range res.AlreadyFollowed {
+		if len(mi.ImportRows) > 0 {
+			d.Matched[id] = byRow[mi.ImportRows[0]]
+		}
+	}
+	onlyInMD := d.OnlyInMangaDex[:0]
+	for _, m := range d.OnlyInMangaDex {
+		if _, ok := res.AlreadyFollowed[m.ID]; !ok {
+			onlyInMD = append(onlyInMD, m)
+		}
+	}
+	d.OnlyInMangaDex = onlyInMD
+
+	for id, mi := range res.New {
+		if len(mi.ImportRows) == 0 {
+			continue
+		}
+		e := byRow[mi.ImportRows[0]]
+		d.Matched[id] = e
+		if e.Status == "" {
+			continue
+		}
+		status := mangadexapi.ReadingStatus(e.Status)
+		if err := client.UpdateMangaStatus(ctx, id, status); err != nil {
+			return len(res.New), fmt.Errorf("update status %s: %w", id, err)
+		}
+		d.statuses[id] = status
+	}
+	// Titles MangaDex refused to follow are still missing there.
+	rejected := make(map[int]bool)
+	for _, first := range res.FollowRejected {
+		for _, mi := range append([]match.MatchInfo{first}, first.Duplicates...) {
+			if len(mi.ImportRows) > 0 {
+				rejected[mi.ImportRows[0]] = true
+			}
+		}
+	}
+	onlyInSource := res.Unmatched
+	for _, ie := range d.OnlyInSource {
+		if len(ie.Source.Rows) > 0 && rejected[ie.Source.Rows[0]] {
+			onlyInSource = append(onlyInSource, ie)
+		}
+	}
+	d.OnlyInSource = onlyInSource
+	return len(res.New), nil
+}
+
+// SourceEntries returns the source list updated with conflicts won by
+// MangaDex, followed by MangaDex-only titles, ready to be written back in
+// the source format and imported into MAL/AniList.
+func (d *Diff) SourceEntries(entries []mangaparser.Entry) []mangaparser.Entry {
+	mdWins := make(map[int]mangadexapi.ReadingStatus)
+	for _, c := range d.Conflicts {
+		if c.Winner == WinnerMangaDex && len(c.Entry.Rows) > 0 {
+			mdWins[c.Entry.Rows[0]] = c.MangaDexStatus
+		}
+	}
+
+	out := make([]mangaparser.Entry, 0, len(entries)+len(d.OnlyInMangaDex))
+	for _, e := range entries {
+		if len(e.Rows) > 0 {
+			if s, ok := mdWins[e.Rows[0]]; ok {
+				e.Status = mangaparser.Status(s)
+				e.UpdatedAt = time.Now()
+			}
+		}
+		out = append(out, e)
+	}
+	for _, m := range d.OnlyInMangaDex {
+		e := mangaparser.Entry{
+			Title:  match.DisplayTitle(m),
+			Status: mangaparser.Status(d.statuses[m.ID]),
+		}
+		if id := m.Attributes.Links["mal"]; id != "" {
+			e.ExternalIDs = append(e.ExternalIDs, "mal:"+id)
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// Record stores the current MangaDex statuses of matched titles so the next
+// newest-wins run can tell which side changed.
+func (d *Diff) Record(state *State) {
+	state.LastSync = time.Now()
+	state.Statuses = make(map[string]mangadexapi.ReadingStatus, len(d.Matched))
+	for id := range d.Matched {
+		if s := d.statuses[id]; s != "" {
+			state.Statuses[id] = s
+		}
+	}
+}
+
+// State is persisted between sync runs of one MangaDex account and source
+// list.
+type State struct {
+	Username string                               `json:"username"`
+	Source   string                               `json:"source"` // absolute path of the source list
+	LastSync time.Time                            `json:"last_sync"`
+	Statuses map[string]mangadexapi.ReadingStatus `json:"statuses"`
+}
+
+// DefaultStatePath returns the state file of username and the source list
+// under the user config dir.
+func DefaultStatePath(username, source string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256([]byte(username + "\x00" + source))
+	name := hex.EncodeToString(h[:])[:16] + ".json"
+	return filepath.Join(dir, "mangadex-import", "sync", name), nil
+}
+
+// LoadState reads the state of username and the source list from path. A
+// missing file, or one recorded for another account or list, yields an
+// empty state.
+func LoadState(path, username, source string) (*State, error) {
+	empty := &State{Username: username, Source: source, Statuses: make(map[string]mangadexapi.ReadingStatus)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return empty, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read sync state: %w", err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("decode sync state: %w", err)
+	}
+	if state.Username != username || state.Source != source {
+		return empty, nil
+	}
+	if state.Statuses == nil {
+		state.Statuses = make(map[string]mangadexapi.ReadingStatus)
+	}
+	return &state, nil
+}
+
+// Save writes the state file, creating its directory if needed.
+func (s *State) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create state dir: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
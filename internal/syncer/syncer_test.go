@@ -0,0 +1,40 @@
+package syncer
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+)
+
+func TestLoadStateOfOtherAccount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	saved := &State{
+		Username: "alice",
+		Source:   "/lists/alice.xml",
+		LastSync: time.Now(),
+		Statuses: map[string]mangadexapi.ReadingStatus{"a": mangadexapi.ReadingStatusReading},
+	}
+	if err := saved.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := LoadState(path, "alice", "/lists/alice.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Statuses) != 1 {
+		t.Errorf("statuses = %v, want the saved one", state.Statuses)
+	}
+
+	for _, other := range [][2]string{{"bob", "/lists/alice.xml"}, {"alice", "/lists/other.xml"}} {
+		state, err := LoadState(path, other[0], other[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(state.Statuses) != 0 || !state.LastSync.IsZero() {
+			t.Errorf("%s, %s: got the state of alice.xml: %+v", other[0], other[1], state)
+		}
+	}
+}
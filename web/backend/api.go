@@ -6,10 +6,21 @@ import (
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/Another0Noob/mangadex-import/internal/checkpoint"
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
+	"golang.org/x/time/rate"
 )
 
 const size = 100
 
+// MangaDex allows about 5 requests per second per IP, so all sessions share
+// one budget on top of their own client limits.
+const (
+	globalRate  = 5
+	globalBurst = 5
+)
+
 // Job is a unit of work that can be executed by the worker.
 type Job interface {
 	// Run executes the job. It receives the API and the session for progress/cancellation.
@@ -25,6 +36,7 @@ type queuedJob struct {
 // API Handler
 type MangaAPI struct {
 	sessions *SessionManager
+	reports  *ReportStore
 
 	// queue so that only one follow job runs at a time
 	jobQueue  chan queuedJob
@@ -39,9 +51,15 @@ type MangaAPI struct {
 	queueSubsMu sync.Mutex
 }
 
-func NewMangaAPI() *MangaAPI {
+// NewMangaAPI creates the API handlers and starts the job worker. clientOpts
+// are applied to every session's MangaDex client after the shared limiter.
+func NewMangaAPI(clientOpts ...mangadexapi.Option) *MangaAPI {
+	opts := append([]mangadexapi.Option{
+		mangadexapi.WithGlobalRateLimiter(rate.NewLimiter(globalRate, globalBurst)),
+	}, clientOpts...)
 	api := &MangaAPI{
-		sessions:   NewSessionManager(),
+		sessions:   NewSessionManager(opts...),
+		reports:    NewReportStore(),
 		queueSize:  size,                       // tune as you like
 		jobQueue:   make(chan queuedJob, size), // buffered queue
 		queueOrder: make([]string, 0, size),
@@ -83,6 +101,10 @@ func NewMangaAPI() *MangaAPI {
 		defer ticker.Stop()
 		for range ticker.C {
 			api.sessions.CleanupStale(24 * time.Hour)
+			api.reports.CleanupStale(24 * time.Hour)
+			if dir, err := checkpoint.DefaultDir(); err == nil {
+				checkpoint.CleanupStale(dir, 7*24*time.Hour)
+			}
 		}
 	}()
 
@@ -9,11 +9,16 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/Another0Noob/mangadex-import/internal/checkpoint"
 	"github.com/Another0Noob/mangadex-import/internal/mangadexapi"
 	"github.com/Another0Noob/mangadex-import/internal/mangaparser"
 	"github.com/Another0Noob/mangadex-import/internal/match"
+	"github.com/Another0Noob/mangadex-import/internal/report"
 )
 
+// searchWorkers is the number of titles a follow job searches concurrently.
+const searchWorkers = 4
+
 type FollowJob struct {
 	Req FollowRequest
 }
@@ -145,6 +150,22 @@ func (api *MangaAPI) runFollowAsync(session *UserSession, req FollowRequest) {
 	}
 	sendProgress("info", fmt.Sprintf("Got %d manga", len(inputManga)), map[string]int{"count": len(inputManga)})
 
+	inputManga, duplicates := match.DedupeEntries(inputManga)
+	if len(duplicates) > 0 {
+		sendProgress("info", fmt.Sprintf("Merged %d duplicate entries, %d unique manga", len(duplicates), len(inputManga)),
+			map[string]any{"duplicates": duplicates})
+	}
+
+	session.Client.SetRetryObserver(func(ev mangadexapi.RetryEvent) {
+		msg := fmt.Sprintf("MangaDex returned %d, retrying in %s", ev.Status, ev.Delay.Round(time.Second))
+		if ev.Status == http.StatusTooManyRequests {
+			msg = fmt.Sprintf("Rate limited by MangaDex, retrying in %s", ev.Delay.Round(time.Second))
+		} else if ev.Err != nil {
+			msg = fmt.Sprintf("Request failed, retrying in %s", ev.Delay.Round(time.Second))
+		}
+		sendProgress("info", msg, map[string]any{"retry_attempt": ev.Attempt, "retry_delay_ms": ev.Delay.Milliseconds()})
+	})
+
 	sendProgress("info", "Authenticating with MangaDex...", nil)
 	authForm := mangadexapi.AuthForm{
 		Username:     req.Username,
@@ -178,8 +199,30 @@ func (api *MangaAPI) runFollowAsync(session *UserSession, req FollowRequest) {
 	sendProgress("progress", fmt.Sprintf("Fuzzy matched %d manga", len(matchResult.Matches)-countDirect),
 		map[string]int{"fuzzy_matches": len(matchResult.Matches) - countDirect})
 
+	// Progress is checkpointed so that uploading the same list again for the
+	// same account resumes after a timeout or cancellation.
+	var cp *checkpoint.Store
+	if dir, err := checkpoint.DefaultDir(); err == nil {
+		cp, err = checkpoint.Open(dir, checkpoint.Key(req.InputFile, req.Username), true)
+		if err != nil {
+			sendProgress("info", fmt.Sprintf("Resume unavailable: %v", err), nil)
+		}
+	}
+	opts := match.SearchOptions{
+		Follow:   true,
+		Followed: match.FollowedIDs(followedManga),
+		Workers:  searchWorkers,
+	}
+	if cp != nil {
+		defer cp.Close()
+		opts.Checkpoint = cp
+		if n := cp.Len(); n > 0 {
+			sendProgress("info", fmt.Sprintf("Resuming, %d manga already searched", n), map[string]int{"resumed": n})
+		}
+	}
+
 	sendProgress("info", "Searching for unmatched manga...", nil)
-	newMatches, stillUnmatched, err := match.SearchAndFollow(ctx, session.Client, matchResult.Unmatched.Import, true)
+	searchResult, err := match.SearchAndFollow(ctx, session.Client, matchResult.Unmatched.Import, opts)
 	if err != nil {
 		// Check if error is due to cancellation
 		if ctx.Err() == context.Canceled {
@@ -189,11 +232,39 @@ func (api *MangaAPI) runFollowAsync(session *UserSession, req FollowRequest) {
 		}
 		return
 	}
+	if cp != nil {
+		cp.Remove()
+	}
+	newMatches, stillUnmatched := searchResult.New, searchResult.Unmatched
+	sendProgress("progress", fmt.Sprintf("Found %d new matches, %d already followed", len(newMatches), len(searchResult.AlreadyFollowed)),
+		map[string]int{"new_matches": len(newMatches), "already_followed": len(searchResult.AlreadyFollowed)})
+
+	rep := report.New(req.InputFilename)
+	rep.AddMatches(matchResult.Matches, "", report.OutcomeAlreadyFollowed)
+	rep.AddMatches(newMatches, "search", report.OutcomeFollowed)
+	rep.AddMatches(searchResult.AlreadyFollowed, "search", report.OutcomeAlreadyFollowedSearch)
+	rep.AddMatches(searchResult.FollowRejected, "search", report.OutcomeFollowRejected)
+	rep.AddUnmatched(stillUnmatched)
+	rep.Sort()
+
+	unmatched := make([]mangaparser.Entry, len(stillUnmatched))
+	for i, e := range stillUnmatched {
+		unmatched[i] = e.Source
+	}
+	inputFormat := mangaparser.FormatList
+	if len(inputManga) > 0 {
+		inputFormat = inputManga[0].Format
+	}
+	api.reports.Put(session.ID, rep, unmatched, inputFormat)
 
 	sendProgress("complete", "Operation completed", map[string]any{
-		"direct_matches":  countDirect,
-		"fuzzy_matches":   len(matchResult.Matches) - countDirect,
-		"new_matches":     len(newMatches),
-		"still_unmatched": len(stillUnmatched),
+		"direct_matches":   countDirect,
+		"fuzzy_matches":    len(matchResult.Matches) - countDirect,
+		"new_matches":      len(newMatches),
+		"already_followed": len(searchResult.AlreadyFollowed),
+		"follow_rejected":  len(searchResult.FollowRejected),
+		"still_unmatched":  len(stillUnmatched),
+		"report":           "/api/report?session_id=" + session.ID,
+		"unmatched":        "/api/unmatched?session_id=" + session.ID,
 	})
 }
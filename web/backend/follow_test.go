@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangadexapi/mdfake"
+	"github.com/Another0Noob/mangadex-import/internal/report"
+)
+
+const malInput = `<myanimelist>
+	<manga><manga_mangadb_id>1</manga_mangadb_id><manga_title>One Piece</manga_title></manga>
+	<manga><manga_mangadb_id>2</manga_mangadb_id><manga_title>Berserk</manga_title></manga>
+	<manga><manga_mangadb_id>3</manga_mangadb_id><manga_title>Not On MangaDex</manga_title></manga>
+</myanimelist>
+`
+
+func TestFollowJob(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+
+	srv := mdfake.New()
+	defer srv.Close()
+	srv.AddManga(mdfake.NewManga("op", "One Piece"), mdfake.NewManga("bs", "Berserk"))
+	srv.Follow("op")
+	// The first search is rate limited and retried.
+	srv.RateLimit(1, 0)
+
+	api := NewMangaAPI(srv.Options()...)
+	session, err := api.sessions.CreateSession(mdfake.ClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth := srv.AuthForm()
+	FollowJob{Req: FollowRequest{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		ClientID:      auth.ClientID,
+		ClientSecret:  auth.ClientSecret,
+		InputFile:     []byte(malInput),
+		InputFilename: "list.xml",
+	}}.Run(api, session)
+
+	var last ProgressUpdate
+	for update := range session.Progress {
+		if update.Type == "error" {
+			t.Fatalf("job failed: %s", update.Message)
+		}
+		last = update
+	}
+	if last.Type != "complete" {
+		t.Fatalf("last update = %+v, want complete", last)
+	}
+	data := last.Data.(map[string]any)
+	if data["new_matches"] != 1 || data["still_unmatched"] != 1 {
+		t.Errorf("complete data = %v", data)
+	}
+
+	if got := srv.Followed(); !slices.Equal(got, []string{"op", "bs"}) {
+		t.Errorf("followed = %v", got)
+	}
+
+	stored, ok := api.reports.get(session.ID)
+	if !ok {
+		t.Fatal("no report stored")
+	}
+	counts := stored.report.Counts()
+	if counts[report.OutcomeAlreadyFollowed] != 1 || counts[report.OutcomeFollowed] != 1 || counts[report.OutcomeUnmatched] != 1 {
+		t.Errorf("report counts = %v", counts)
+	}
+
+	rec := httptest.NewRecorder()
+	api.HandleUnmatched(rec, httptest.NewRequest(http.MethodGet, "/api/unmatched?session_id="+session.ID+"&format=list", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "Not On MangaDex\n" {
+		t.Errorf("unmatched download = %d %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,132 @@
+package backend
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Another0Noob/mangadex-import/internal/mangaparser"
+	"github.com/Another0Noob/mangadex-import/internal/report"
+)
+
+// ReportStore keeps finished match reports and unmatched entries so they can
+// be downloaded after the session that produced them has been removed.
+type ReportStore struct {
+	mu      sync.Mutex
+	reports map[string]storedReport // session ID -> report
+}
+
+type storedReport struct {
+	report      *report.Report
+	unmatched   []mangaparser.Entry
+	inputFormat mangaparser.Format
+	createdAt   time.Time
+}
+
+func NewReportStore() *ReportStore {
+	return &ReportStore{
+		reports: make(map[string]storedReport),
+	}
+}
+
+func (rs *ReportStore) Put(sessionID string, rep *report.Report, unmatched []mangaparser.Entry, inputFormat mangaparser.Format) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.reports[sessionID] = storedReport{
+		report:      rep,
+		unmatched:   unmatched,
+		inputFormat: inputFormat,
+		createdAt:   time.Now(),
+	}
+}
+
+func (rs *ReportStore) get(sessionID string) (storedReport, bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	sr, ok := rs.reports[sessionID]
+	return sr, ok
+}
+
+// CleanupStale removes reports older than maxAge
+func (rs *ReportStore) CleanupStale(maxAge time.Duration) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	now := time.Now()
+	for id, sr := range rs.reports {
+		if now.Sub(sr.createdAt) > maxAge {
+			delete(rs.reports, id)
+		}
+	}
+}
+
+var reportContentTypes = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"json": "application/json",
+	"html": "text/html; charset=utf-8",
+}
+
+// HandleReport serves the report of a finished session as a download
+func (api *MangaAPI) HandleReport(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("session_id")
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "html"
+	}
+
+	contentType, ok := reportContentTypes[format]
+	if !ok {
+		http.Error(w, "format must be csv, json or html", http.StatusBadRequest)
+		return
+	}
+
+	sr, ok := api.reports.get(sessionID)
+	if !ok {
+		http.Error(w, "No report for session", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="mangadex-report.%s"`, format))
+	if err := sr.report.Write(w, format); err != nil {
+		http.Error(w, "Failed to write report", http.StatusInternalServerError)
+	}
+}
+
+// HandleUnmatched serves the unmatched entries of a finished session, either
+// in the uploaded file's format (format=input) or as a plain list (format=list)
+func (api *MangaAPI) HandleUnmatched(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("session_id")
+
+	sr, ok := api.reports.get(sessionID)
+	if !ok {
+		http.Error(w, "No report for session", http.StatusNotFound)
+		return
+	}
+
+	var format mangaparser.Format
+	switch r.URL.Query().Get("format") {
+	case "", "input":
+		format = sr.inputFormat
+	case "list":
+		format = mangaparser.FormatList
+	default:
+		http.Error(w, "format must be input or list", http.StatusBadRequest)
+		return
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	switch format {
+	case mangaparser.FormatMAL:
+		contentType = "application/xml"
+	case mangaparser.FormatComick:
+		contentType = "text/csv; charset=utf-8"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="unmatched%s"`, format.Extension()))
+	if err := mangaparser.Write(w, format, sr.unmatched); err != nil {
+		http.Error(w, "Failed to write unmatched entries", http.StatusInternalServerError)
+	}
+}
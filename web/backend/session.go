@@ -30,11 +30,14 @@ type UserSession struct {
 type SessionManager struct {
 	mu       sync.RWMutex
 	sessions map[string]*UserSession
+
+	clientOpts []mangadexapi.Option // applied to every session's client
 }
 
-func NewSessionManager() *SessionManager {
+func NewSessionManager(clientOpts ...mangadexapi.Option) *SessionManager {
 	return &SessionManager{
-		sessions: make(map[string]*UserSession),
+		sessions:   make(map[string]*UserSession),
+		clientOpts: clientOpts,
 	}
 }
 
@@ -52,7 +55,7 @@ func (sm *SessionManager) CreateSession(clientID string) (*UserSession, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	session := &UserSession{
 		ID:        uuid.New().String(),
-		Client:    mangadexapi.NewClient(),
+		Client:    mangadexapi.NewClient(sm.clientOpts...),
 		Progress:  make(chan ProgressUpdate, 100),
 		Ctx:       ctx,
 		CancelFn:  cancel,
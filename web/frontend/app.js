@@ -24,6 +24,39 @@ function appendProgress(text, cls) {
   li.scrollIntoView({ behavior: "smooth", block: "end" });
 }
 
+// add download links for the finished report in each format
+function appendReportLinks(reportURL) {
+  const li = document.createElement("li");
+  li.className = "complete";
+  li.append("Download report: ");
+  ["html", "csv", "json"].forEach((format, i) => {
+    if (i > 0) li.append(" · ");
+    const a = document.createElement("a");
+    a.href = `${reportURL}&format=${format}`;
+    a.textContent = format.toUpperCase();
+    li.appendChild(a);
+  });
+  progressList.appendChild(li);
+}
+
+// add download links for unmatched entries (original format and plain list)
+function appendUnmatchedLinks(unmatchedURL) {
+  const li = document.createElement("li");
+  li.className = "complete";
+  li.append("Download unmatched: ");
+  [
+    ["input", "Original format"],
+    ["list", "Plain list"],
+  ].forEach(([format, label], i) => {
+    if (i > 0) li.append(" · ");
+    const a = document.createElement("a");
+    a.href = `${unmatchedURL}&format=${format}`;
+    a.textContent = label;
+    li.appendChild(a);
+  });
+  progressList.appendChild(li);
+}
+
 // Start import: send multipart form with file and credentials
 async function startImport() {
   const username = usernameInput.value.trim();
@@ -119,6 +152,9 @@ function startProgressSSE(sessionId) {
 
         case "complete":
           appendProgress(data.message || "Import complete", "complete");
+          if (data.data?.report) appendReportLinks(data.data.report);
+          if (data.data?.unmatched && data.data.still_unmatched > 0)
+            appendUnmatchedLinks(data.data.unmatched);
           cleanupAfterFinish();
           break;
 
@@ -22,6 +22,8 @@ func HandleBack(mux *http.ServeMux) {
 	mux.HandleFunc("/api/progress", api.HandleProgress)
 	mux.HandleFunc("/api/cancel", api.HandleCancel)
 	mux.HandleFunc("/api/queue", api.HandleQueue)
+	mux.HandleFunc("/api/report", api.HandleReport)
+	mux.HandleFunc("/api/unmatched", api.HandleUnmatched)
 }
 
 func HandleFront(mux *http.ServeMux) {